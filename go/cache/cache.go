@@ -1,130 +1,273 @@
 package cache
 
 import (
+	"container/list"
+	"fmt"
 	"slices"
+	"sync"
 	"time"
 )
 
-type CacheEntry struct {
-	Key       string
-	Value     interface{}
+type CacheEntry[K comparable, V any] struct {
+	Key       K
+	Value     V
 	CreatedAt time.Time
 	ExpiresAt time.Time
 	HitCount  int
+	Cost      int64 // capacity units charged for this entry, see WithMaxCost
+
+	element   *list.Element // owned by the eviction policy
+	heapIndex int           // position in Cache.expiry, -1 if the entry never expires
 }
 
-type Cache struct {
-	entries     map[string]*CacheEntry
-	maxSize     int
-	accessOrder []string
+type Cache[K comparable, V any] struct {
+	entries    map[K]*CacheEntry[K, V]
+	maxSize    int
+	policyKind PolicyKind
+	policy     EvictionPolicy[K, V]
+	expiry     expiryHeap[K, V]
+	mu         sync.Mutex
+	clock      Clock
+
+	loads        map[K]*loadCall[V]
+	failures     map[K]*loadFailure
+	negativeTTL  time.Duration
+	refreshAhead time.Duration
+
+	maxCost   int64
+	totalCost int64
+	sizer     func(V) int64
+
+	stats      Stats
+	evictHooks []func(key K, value V, reason EvictionReason)
+	notices    []evictionNotice[K, V]
+
+	stopJanitor chan struct{}
+	janitorDone chan struct{}
+	closeOnce   sync.Once
 }
 
-func NewCache(maxSize int) *Cache {
-	return &Cache{
-		entries: make(map[string]*CacheEntry),
-		maxSize: maxSize,
+// New creates a cache holding at most maxSize entries (0 means unlimited).
+// Entries are evicted by LRU unless WithEvictionPolicy selects another policy.
+// Pass WithJanitor to sweep expired entries in the background; such a cache
+// must be stopped with Close.
+func New[K comparable, V any](maxSize int, opts ...Option) *Cache[K, V] {
+	o := options{policy: PolicyLRU, clock: RealClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Cache[K, V]{
+		entries:    make(map[K]*CacheEntry[K, V]),
+		maxSize:    maxSize,
+		clock:      o.clock,
+		policyKind: o.policy,
+		policy:     newEvictionPolicy[K, V](o.policy, maxSize),
+
+		loads:        make(map[K]*loadCall[V]),
+		failures:     make(map[K]*loadFailure),
+		negativeTTL:  o.negativeTTL,
+		refreshAhead: o.refreshAhead,
+
+		maxCost: o.maxCost,
+		stats:   Stats{Evictions: make(map[EvictionReason]int)},
+	}
+
+	if o.sizer != nil {
+		sizer, ok := o.sizer.(func(V) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: WithSizer got %T, want func(%T) int64", o.sizer, *new(V)))
+		}
+		c.sizer = sizer
 	}
+
+	if o.sweepInterval > 0 {
+		c.startJanitor(o.sweepInterval, o.maxSweep)
+	}
+
+	return c
+}
+
+// NewCache creates a cache with string keys and untyped values,
+// the shape Cache had before it became generic.
+func NewCache(maxSize int, opts ...Option) *Cache[string, interface{}] {
+	return New[string, interface{}](maxSize, opts...)
 }
 
 // Set adds or updates a key with a TTL (time-to-live).
 // If TTL is 0, the entry never expires.
-// If cache is full (at maxSize), remove the least recently accessed entry before adding.
+// If cache is full (at maxSize), remove the entry chosen by the eviction policy before adding.
+// With WithMaxCost, entries are also evicted until the new entry's cost fits the budget;
+// the cost comes from WithSizer, or is 1 without a sizer.
 // Returns true if a new entry was added, false if an existing entry was updated.
-func (c *Cache) Set(key string, value interface{}, ttl time.Duration) bool {
+func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.invalidateLoad(key)
+	return c.set(key, value, ttl, c.costOf(value))
+}
+
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration, cost int64) bool {
+	if cost < 0 {
+		return false
+	}
 	cache, exists := c.entries[key]
+
+	// An entry larger than the whole budget can never fit
+	if c.maxCost > 0 && cost > c.maxCost {
+		if exists {
+			c.stats.EvictedCost += cache.Cost
+			c.removeEntry(cache, ReasonCapacity)
+		}
+		return false
+	}
+
 	if exists {
 		cache.Value = value
-		cache.CreatedAt = time.Now()
+		cache.CreatedAt = c.clock.Now()
 		if ttl > 0 {
 			cache.ExpiresAt = cache.CreatedAt.Add(ttl)
 		} else {
 			cache.ExpiresAt = time.Time{}
 		}
+		c.expiry.update(cache)
+
+		c.totalCost += cost - cache.Cost
+		cache.Cost = cost
+		if c.maxCost > 0 && c.totalCost > c.maxCost {
+			// Take the entry out of the policy so it cannot be picked as its own victim
+			c.policy.Remove(cache)
+			c.evictFor(0, false)
+			c.policy.Add(cache)
+		}
 		return false
 	}
 
-	if c.maxSize > 0 && len(c.entries) >= c.maxSize {
-		lruKey := c.accessOrder[0]
-		delete(c.entries, lruKey)
-		c.accessOrder = c.accessOrder[1:]
-	}
+	c.evictFor(cost, true)
 
-	createdAt := time.Now()
+	createdAt := c.clock.Now()
 	var expiredAt time.Time
 	if ttl > 0 {
 		expiredAt = createdAt.Add(ttl)
 	} else {
 		expiredAt = time.Time{}
 	}
-	newEntry := &CacheEntry{
+	newEntry := &CacheEntry[K, V]{
 		Key:       key,
 		Value:     value,
 		CreatedAt: createdAt,
 		ExpiresAt: expiredAt,
 		HitCount:  0,
+		Cost:      cost,
+		heapIndex: -1,
 	}
-	c.entries[key] = newEntry
-	c.accessOrder = append(c.accessOrder, key)
+	c.insert(newEntry)
 	return true
 }
 
-func removeKeyFromSlice(slice []string, key string) []string {
-	for i, v := range slice {
-		if v == key {
-			return append(slice[:i], slice[i+1:]...)
+// insert adds a new entry to the map, the eviction policy and the expiry heap.
+// Callers must make room first.
+func (c *Cache[K, V]) insert(entry *CacheEntry[K, V]) {
+	c.policy.Add(entry)
+	c.expiry.update(entry)
+	c.entries[entry.Key] = entry
+	c.totalCost += entry.Cost
+}
+
+// evictFor evicts entries chosen by the policy until an entry of the given cost fits.
+// newEntry reports whether the entry also needs a free slot under maxSize.
+func (c *Cache[K, V]) evictFor(cost int64, newEntry bool) {
+	for (newEntry && c.maxSize > 0 && len(c.entries) >= c.maxSize) ||
+		(c.maxCost > 0 && c.totalCost+cost > c.maxCost) {
+		victim := c.policy.Victim()
+		if victim == nil {
+			return
 		}
+		c.stats.EvictedCost += victim.Cost
+		c.removeEntry(victim, ReasonCapacity)
+	}
+}
+
+// removeEntry deletes an entry from the map, the eviction policy and the expiry heap,
+// and queues an OnEvict notification that unlock delivers.
+func (c *Cache[K, V]) removeEntry(entry *CacheEntry[K, V], reason EvictionReason) {
+	c.stats.Evictions[reason]++
+	if len(c.evictHooks) > 0 {
+		c.notices = append(c.notices, evictionNotice[K, V]{entry.Key, entry.Value, reason})
 	}
-	return slice
+	c.unlink(entry)
+}
+
+// unlink drops an entry from the cache without reporting it as an eviction.
+// Callers must hold c.mu.
+func (c *Cache[K, V]) unlink(entry *CacheEntry[K, V]) {
+	c.totalCost -= entry.Cost
+	delete(c.entries, entry.Key)
+	c.policy.Remove(entry)
+	c.expiry.remove(entry)
 }
 
 // Get retrieves a value by key.
 // Returns (value, true) if found and not expired.
-// Returns (nil, false) if not found or expired.
+// Returns (zero value, false) if not found or expired.
 // If expired, the entry should be deleted.
 // Increments HitCount on successful get.
-func (c *Cache) Get(key string) (interface{}, bool) {
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	var zero V
 	entry, exists := c.entries[key]
 	if !exists {
-		return nil, false
+		c.stats.Misses++
+		return zero, false
 	}
 
-	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
-		delete(c.entries, key)
-		c.accessOrder = removeKeyFromSlice(c.accessOrder, key)
-		return nil, false
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		c.removeEntry(entry, ReasonExpired)
+		c.stats.Misses++
+		return zero, false
 	}
 
+	c.stats.Hits++
 	entry.HitCount++
-	c.accessOrder = removeKeyFromSlice(c.accessOrder, key)
-	c.accessOrder = append(c.accessOrder, key)
+	c.policy.Access(entry)
 	return entry.Value, true
 }
 
 // Delete removes a key from the cache.
 // Returns true if the key existed, false otherwise.
-func (c *Cache) Delete(key string) bool {
-	_, exists := c.entries[key]
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	delete(c.failures, key)
+	c.invalidateLoad(key)
+
+	entry, exists := c.entries[key]
 	if !exists {
 		return false
 	}
 
-	delete(c.entries, key)
-	c.accessOrder = removeKeyFromSlice(c.accessOrder, key)
+	c.removeEntry(entry, ReasonDeleted)
 	return true
 }
 
 // Has checks if a key exists and is not expired.
 // Does NOT increment HitCount.
 // Deletes the entry if expired.
-func (c *Cache) Has(key string) bool {
+func (c *Cache[K, V]) Has(key K) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
 	entry, exists := c.entries[key]
 	if !exists {
 		return false
 	}
 
-	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
-		delete(c.entries, key)
-		c.accessOrder = removeKeyFromSlice(c.accessOrder, key)
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		c.removeEntry(entry, ReasonExpired)
 		return false
 	}
 
@@ -132,13 +275,19 @@ func (c *Cache) Has(key string) bool {
 }
 
 // Size returns the number of entries in the cache (including expired ones).
-func (c *Cache) Size() int {
+func (c *Cache[K, V]) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return len(c.entries)
 }
 
 // Keys returns all keys in the cache (including expired ones).
-func (c *Cache) Keys() []string {
-	keys := make([]string, 0, len(c.entries))
+func (c *Cache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]K, 0, len(c.entries))
 	for key := range c.entries {
 		keys = append(keys, key)
 	}
@@ -147,63 +296,67 @@ func (c *Cache) Keys() []string {
 
 // Clear removes all entries from the cache.
 // Returns the number of entries removed.
-func (c *Cache) Clear() int {
+func (c *Cache[K, V]) Clear() int {
+	c.mu.Lock()
+	defer c.unlock()
+
 	removed := len(c.entries)
-	c.entries = make(map[string]*CacheEntry)
-	c.accessOrder = []string{}
+	c.stats.Evictions[ReasonCleared] += removed
+	if len(c.evictHooks) > 0 {
+		for key, entry := range c.entries {
+			c.notices = append(c.notices, evictionNotice[K, V]{key, entry.Value, ReasonCleared})
+		}
+	}
+	c.entries = make(map[K]*CacheEntry[K, V])
+	c.policy = newEvictionPolicy[K, V](c.policyKind, c.maxSize)
+	c.expiry = nil
+	c.totalCost = 0
+	c.failures = make(map[K]*loadFailure)
+	for key := range c.loads {
+		c.invalidateLoad(key)
+	}
 	return removed
 }
 
 // CleanupExpired removes all expired entries.
 // Returns the number of entries removed.
-func (c *Cache) CleanupExpired() int {
-	removed := 0
-	now := time.Now()
-	for key, entry := range c.entries {
-		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
-			delete(c.entries, key)
-			c.accessOrder = removeKeyFromSlice(c.accessOrder, key)
-			removed++
-		}
-	}
-	return removed
-}
+func (c *Cache[K, V]) CleanupExpired() int {
+	c.mu.Lock()
+	defer c.unlock()
 
-// GetStats returns cache statistics.
-// Returns: (totalEntries, totalHits, expiredCount)
-// expiredCount = number of currently expired entries (without deleting them)
-func (c *Cache) GetStats() (int, int, int) {
-	totalEntries := len(c.entries)
-	totalHits := 0
-	expiredCount := 0
-	now := time.Now()
-	for _, entry := range c.entries {
-		totalHits += entry.HitCount
-		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
-			expiredCount++
-		}
-	}
-	return totalEntries, totalHits, expiredCount
+	return c.removeExpired(c.clock.Now(), 0)
 }
 
 // GetMostAccessed returns the top N entries by HitCount, sorted descending.
 // Only includes non-expired entries.
 // Returns fewer than N if there aren't enough non-expired entries.
-func (c *Cache) GetMostAccessed(n int) []*CacheEntry {
-	entries := make([]*CacheEntry, 0, n)
+// The returned entries are copies, so they are safe to read while the cache keeps changing.
+func (c *Cache[K, V]) GetMostAccessed(n int) []*CacheEntry[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]*CacheEntry[K, V], 0, n)
+	now := c.clock.Now()
 	for _, entry := range c.entries {
-		if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
 			continue
 		}
 		entries = append(entries, entry)
 	}
 
-	slices.SortFunc(entries, func(a, b *CacheEntry) int {
+	slices.SortFunc(entries, func(a, b *CacheEntry[K, V]) int {
 		return b.HitCount - a.HitCount
 	})
 
 	if len(entries) > n {
-		return entries[:n]
+		entries = entries[:n]
+	}
+
+	for i, entry := range entries {
+		snapshot := *entry
+		snapshot.element = nil
+		snapshot.heapIndex = -1
+		entries[i] = &snapshot
 	}
 
 	return entries
@@ -6,7 +6,11 @@ import (
 )
 
 func TestNewCache(t *testing.T) {
-	c := NewCache(10)
+	testNewCache(t)
+}
+
+func testNewCache(t *testing.T, opts ...Option) {
+	c := NewCache(10, opts...)
 	if c == nil {
 		t.Fatal("cache should not be nil")
 	}
@@ -16,7 +20,11 @@ func TestNewCache(t *testing.T) {
 }
 
 func TestSetAndGet(t *testing.T) {
-	c := NewCache(10)
+	testSetAndGet(t)
+}
+
+func testSetAndGet(t *testing.T, opts ...Option) {
+	c := NewCache(10, opts...)
 
 	// Set new key
 	isNew := c.Set("key1", "value1", 0)
@@ -52,7 +60,12 @@ func TestSetAndGet(t *testing.T) {
 }
 
 func TestExpiration(t *testing.T) {
-	c := NewCache(10)
+	testExpiration(t)
+}
+
+func testExpiration(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, append(opts, WithClock(clock))...)
 
 	// Set with short TTL
 	c.Set("key1", "value1", 50*time.Millisecond)
@@ -63,7 +76,7 @@ func TestExpiration(t *testing.T) {
 	}
 
 	// Wait for expiration
-	time.Sleep(60 * time.Millisecond)
+	clock.Advance(60 * time.Millisecond)
 
 	// Should be gone
 	if _, ok := c.Get("key1"); ok {
@@ -77,7 +90,11 @@ func TestExpiration(t *testing.T) {
 }
 
 func TestHas(t *testing.T) {
-	c := NewCache(10)
+	testHas(t)
+}
+
+func testHas(t *testing.T, opts ...Option) {
+	c := NewCache(10, opts...)
 	c.Set("key1", "value1", 0)
 
 	if !c.Has("key1") {
@@ -103,10 +120,15 @@ func TestHas(t *testing.T) {
 }
 
 func TestHasExpiration(t *testing.T) {
-	c := NewCache(10)
+	testHasExpiration(t)
+}
+
+func testHasExpiration(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, append(opts, WithClock(clock))...)
 	c.Set("key1", "value1", 50*time.Millisecond)
 
-	time.Sleep(60 * time.Millisecond)
+	clock.Advance(60 * time.Millisecond)
 
 	if c.Has("key1") {
 		t.Error("Has should return false for expired key")
@@ -117,7 +139,11 @@ func TestHasExpiration(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
-	c := NewCache(10)
+	testDelete(t)
+}
+
+func testDelete(t *testing.T, opts ...Option) {
+	c := NewCache(10, opts...)
 	c.Set("key1", "value1", 0)
 
 	// Delete existing
@@ -135,7 +161,11 @@ func TestDelete(t *testing.T) {
 }
 
 func TestSizeAndKeys(t *testing.T) {
-	c := NewCache(10)
+	testSizeAndKeys(t)
+}
+
+func testSizeAndKeys(t *testing.T, opts ...Option) {
+	c := NewCache(10, opts...)
 
 	c.Set("key1", "value1", 0)
 	c.Set("key2", "value2", 0)
@@ -161,7 +191,11 @@ func TestSizeAndKeys(t *testing.T) {
 }
 
 func TestClear(t *testing.T) {
-	c := NewCache(10)
+	testClear(t)
+}
+
+func testClear(t *testing.T, opts ...Option) {
+	c := NewCache(10, opts...)
 
 	c.Set("key1", "value1", 0)
 	c.Set("key2", "value2", 0)
@@ -177,13 +211,18 @@ func TestClear(t *testing.T) {
 }
 
 func TestCleanupExpired(t *testing.T) {
-	c := NewCache(10)
+	testCleanupExpired(t)
+}
+
+func testCleanupExpired(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, append(opts, WithClock(clock))...)
 
 	c.Set("key1", "value1", 50*time.Millisecond)
 	c.Set("key2", "value2", 50*time.Millisecond)
 	c.Set("key3", "value3", 0) // never expires
 
-	time.Sleep(60 * time.Millisecond)
+	clock.Advance(60 * time.Millisecond)
 
 	removed := c.CleanupExpired()
 	if removed != 2 {
@@ -227,7 +266,11 @@ func TestMaxSize(t *testing.T) {
 }
 
 func TestMaxSizeUnlimited(t *testing.T) {
-	c := NewCache(0) // unlimited
+	testMaxSizeUnlimited(t)
+}
+
+func testMaxSizeUnlimited(t *testing.T, opts ...Option) {
+	c := NewCache(0, opts...) // unlimited
 
 	for i := 0; i < 100; i++ {
 		c.Set(string(rune('a'+i)), i, 0)
@@ -239,7 +282,12 @@ func TestMaxSizeUnlimited(t *testing.T) {
 }
 
 func TestGetStats(t *testing.T) {
-	c := NewCache(10)
+	testGetStats(t)
+}
+
+func testGetStats(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, append(opts, WithClock(clock))...)
 
 	c.Set("key1", "value1", 0)
 	c.Set("key2", "value2", 50*time.Millisecond)
@@ -250,22 +298,35 @@ func TestGetStats(t *testing.T) {
 	c.Get("key1")
 	c.Get("key3")
 
-	time.Sleep(60 * time.Millisecond)
+	clock.Advance(60 * time.Millisecond)
 
-	total, hits, expired := c.GetStats()
-	if total != 3 {
-		t.Errorf("expected 3 total, got %d", total)
+	c.Get("key999")
+
+	stats := c.GetStats()
+	if stats.Entries != 3 {
+		t.Errorf("expected 3 total, got %d", stats.Entries)
+	}
+	if stats.Hits != 3 {
+		t.Errorf("expected 3 hits, got %d", stats.Hits)
 	}
-	if hits != 3 {
-		t.Errorf("expected 3 hits, got %d", hits)
+	if stats.Misses != 1 {
+		t.Errorf("expected 1 miss, got %d", stats.Misses)
 	}
-	if expired != 1 {
-		t.Errorf("expected 1 expired, got %d", expired)
+	if stats.HitRatio != 0.75 {
+		t.Errorf("expected hit ratio 0.75, got %v", stats.HitRatio)
+	}
+	if stats.Expired != 1 {
+		t.Errorf("expected 1 expired, got %d", stats.Expired)
 	}
 }
 
 func TestGetMostAccessed(t *testing.T) {
-	c := NewCache(10)
+	testGetMostAccessed(t)
+}
+
+func testGetMostAccessed(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, append(opts, WithClock(clock))...)
 
 	c.Set("key1", "value1", 0)
 	c.Set("key2", "value2", 0)
@@ -285,7 +346,7 @@ func TestGetMostAccessed(t *testing.T) {
 	c.Get("key4")
 	c.Get("key4")
 
-	time.Sleep(60 * time.Millisecond)
+	clock.Advance(60 * time.Millisecond)
 
 	// Get top 2 (should exclude expired key4)
 	top := c.GetMostAccessed(2)
@@ -307,18 +368,142 @@ func TestGetMostAccessed(t *testing.T) {
 }
 
 func TestSetUpdatesExpiration(t *testing.T) {
-	c := NewCache(10)
+	testSetUpdatesExpiration(t)
+}
+
+func testSetUpdatesExpiration(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, append(opts, WithClock(clock))...)
 
 	c.Set("key1", "value1", 50*time.Millisecond)
-	time.Sleep(30 * time.Millisecond)
+	clock.Advance(30 * time.Millisecond)
 
 	// Update with new TTL
 	c.Set("key1", "value2", 100*time.Millisecond)
 
-	time.Sleep(40 * time.Millisecond)
+	clock.Advance(40 * time.Millisecond)
 
 	// Should still exist (new TTL from second Set)
 	if !c.Has("key1") {
 		t.Error("key should still exist after TTL update")
 	}
 }
+
+func TestLRUOrderAfterDelete(t *testing.T) {
+	c := NewCache(3)
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+
+	// Removing the middle entry must keep the rest of the order intact
+	c.Delete("key2")
+	c.Get("key1")
+	c.Set("key4", "value4", 0)
+	c.Set("key5", "value5", 0)
+
+	// key3 was least recently accessed, then key1 was refreshed
+	if c.Has("key3") {
+		t.Error("key3 should be evicted")
+	}
+	if !c.Has("key1") || !c.Has("key4") || !c.Has("key5") {
+		t.Error("key1, key4, key5 should exist")
+	}
+	if order := c.policy.(*lruPolicy[string, interface{}]).order; order.Len() != c.Size() {
+		t.Errorf("access order length %d should match size %d", order.Len(), c.Size())
+	}
+}
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func TestTypedCache(t *testing.T) {
+	c := New[int, testUser](2)
+
+	c.Set(1, testUser{Name: "alice", Age: 30}, 0)
+	c.Set(2, testUser{Name: "bob", Age: 25}, 0)
+
+	user, ok := c.Get(1)
+	if !ok || user.Name != "alice" {
+		t.Errorf("expected alice, got %+v", user)
+	}
+
+	// Miss returns the zero value of V
+	user, ok = c.Get(99)
+	if ok || user != (testUser{}) {
+		t.Errorf("expected zero value on miss, got %+v", user)
+	}
+
+	// key2 is least recently accessed
+	c.Set(3, testUser{Name: "carol", Age: 41}, 0)
+	if c.Has(2) {
+		t.Error("key 2 should be evicted")
+	}
+
+	top := c.GetMostAccessed(1)
+	if len(top) != 1 || top[0].Key != 1 || top[0].Value.Age != 30 {
+		t.Errorf("expected key 1 to be most accessed, got %+v", top)
+	}
+
+	keys := c.Keys()
+	if len(keys) != 2 {
+		t.Errorf("expected 2 keys, got %v", keys)
+	}
+}
+
+func TestTypedCacheWithPolicies(t *testing.T) {
+	for _, policy := range allPolicies {
+		c := New[int, []byte](3, WithEvictionPolicy(policy))
+		for i := 0; i < 10; i++ {
+			c.Set(i, make([]byte, i), 0)
+			c.Get(i)
+		}
+		if c.Size() != 3 {
+			t.Errorf("%s: expected size 3, got %d", policy, c.Size())
+		}
+	}
+}
+
+// ==================== Benchmarks ====================
+
+const benchmarkLargeSize = 1_000_000
+
+func newFullCache(n int) (*Cache[string, interface{}], []string) {
+	c := NewCache(n)
+	keys := benchmarkKeys(n)
+	for i, key := range keys {
+		c.Set(key, i, 0)
+	}
+	return c, keys
+}
+
+func BenchmarkCacheGet1M(b *testing.B) {
+	c, keys := newFullCache(benchmarkLargeSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkCacheSetEvict1M(b *testing.B) {
+	c, _ := newFullCache(benchmarkLargeSize)
+	newKeys := benchmarkKeys(benchmarkLargeSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Every insert hits capacity and evicts the least recently accessed key
+		c.Set("new-"+newKeys[i%len(newKeys)], i, 0)
+	}
+}
+
+func BenchmarkCacheDeleteHas1M(b *testing.B) {
+	c, keys := newFullCache(benchmarkLargeSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		c.Delete(key)
+		c.Has(key)
+		c.Set(key, i, 0)
+	}
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the cache what time it is, for TTLs and load latency.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the wall clock; caches use it unless WithClock says otherwise.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+// FakeClock is a Clock that only moves when told to, so TTL behaviour can be
+// tested without sleeping. It is safe for concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockExpiryBoundary(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	c := NewCache(10, WithClock(clock))
+
+	c.Set("key1", "value1", time.Minute)
+	if entry := c.GetMostAccessed(1)[0]; !entry.CreatedAt.Equal(start) || !entry.ExpiresAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("timestamps should come from the clock, got %v and %v", entry.CreatedAt, entry.ExpiresAt)
+	}
+
+	// An entry is still valid at exactly ExpiresAt
+	clock.Advance(time.Minute)
+	if !c.Has("key1") {
+		t.Error("key1 should still exist at its expiry time")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if c.Has("key1") {
+		t.Error("key1 should expire right after its expiry time")
+	}
+}
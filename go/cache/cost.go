@@ -0,0 +1,24 @@
+package cache
+
+import "time"
+
+// SetWithCost is like Set but charges the entry an explicit cost against the
+// WithMaxCost budget instead of asking the sizer.
+// An entry costing more than the whole budget is not stored, and any
+// previous value for the key is removed; SetWithCost then returns false.
+// A negative cost is rejected: the cache is left as it was and SetWithCost
+// returns false.
+func (c *Cache[K, V]) SetWithCost(key K, value V, ttl time.Duration, cost int64) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.invalidateLoad(key)
+	return c.set(key, value, ttl, cost)
+}
+
+func (c *Cache[K, V]) costOf(value V) int64 {
+	if c.sizer == nil {
+		return 1
+	}
+	return c.sizer(value)
+}
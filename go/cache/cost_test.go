@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCostBudgetEvictsUntilFit(t *testing.T) {
+	c := New[string, []byte](0, WithMaxCost(100), WithSizer(func(v []byte) int64 { return int64(len(v)) }))
+
+	c.Set("a", make([]byte, 40), 0)
+	c.Set("b", make([]byte, 40), 0)
+	c.Get("a") // b becomes least recently used
+
+	// 30 more bytes need 10 freed; only b has to go
+	c.Set("c", make([]byte, 30), 0)
+	if c.Has("b") {
+		t.Error("b should be evicted to make room")
+	}
+	if !c.Has("a") || !c.Has("c") {
+		t.Error("a and c should exist")
+	}
+
+	// 90 bytes need both a and c to go
+	c.Set("d", make([]byte, 90), 0)
+	if c.Size() != 1 || !c.Has("d") {
+		t.Errorf("only d should remain, got %v", c.Keys())
+	}
+
+	stats := c.GetStats()
+	if stats.Cost != 90 {
+		t.Errorf("expected current cost 90, got %d", stats.Cost)
+	}
+	if stats.EvictedCost != 110 {
+		t.Errorf("expected evicted cost 110, got %d", stats.EvictedCost)
+	}
+	if stats.Evictions[ReasonCapacity] != 3 {
+		t.Errorf("expected 3 evictions, got %d", stats.Evictions[ReasonCapacity])
+	}
+}
+
+func TestSetWithCost(t *testing.T) {
+	c := NewCache(0, WithMaxCost(10))
+
+	c.SetWithCost("key1", "value1", 0, 4)
+	c.SetWithCost("key2", "value2", 0, 4)
+	c.SetWithCost("key3", "value3", 0, 4)
+
+	if c.Has("key1") {
+		t.Error("key1 should be evicted when the budget is exceeded")
+	}
+	if current := c.GetStats().Cost; current != 8 {
+		t.Errorf("expected current cost 8, got %d", current)
+	}
+}
+
+func TestCostDefaultsToOneWithoutSizer(t *testing.T) {
+	c := NewCache(0, WithMaxCost(3))
+
+	for i := 0; i < 5; i++ {
+		c.Set("key"+strconv.Itoa(i), i, 0)
+	}
+
+	if c.Size() != 3 {
+		t.Errorf("budget of 3 should hold 3 unit-cost entries, got %d", c.Size())
+	}
+}
+
+func TestCostOversizedEntryRejected(t *testing.T) {
+	c := NewCache(0, WithMaxCost(10))
+
+	c.SetWithCost("small", "value", 0, 5)
+	c.SetWithCost("key1", "value1", 0, 5)
+
+	if c.SetWithCost("huge", "value", 0, 11) {
+		t.Error("entry larger than the budget should be rejected")
+	}
+	if c.Has("huge") {
+		t.Error("rejected entry should not be stored")
+	}
+	if !c.Has("small") || !c.Has("key1") {
+		t.Error("rejection should not evict other entries")
+	}
+
+	// Growing an existing key past the budget drops the stale value
+	c.SetWithCost("key1", "bigger", 0, 20)
+	if c.Has("key1") {
+		t.Error("key1 should be removed when its new value cannot fit")
+	}
+	stats := c.GetStats()
+	if stats.Cost != 5 {
+		t.Errorf("expected current cost 5, got %d", stats.Cost)
+	}
+	if stats.EvictedCost != 5 || stats.Evictions[ReasonCapacity] != 1 {
+		t.Errorf("expected the dropped value's cost counted as evicted, got %d", stats.EvictedCost)
+	}
+}
+
+func TestNegativeCostRejected(t *testing.T) {
+	c := NewCache(0, WithMaxCost(10))
+	c.SetWithCost("key1", "value1", 0, 4)
+
+	if c.SetWithCost("key1", "value2", 0, -1) || c.SetWithCost("key2", "value2", 0, -1) {
+		t.Error("expected a negative cost to be rejected")
+	}
+	if val, _ := c.Get("key1"); val != "value1" || c.Has("key2") {
+		t.Errorf("expected the cache unchanged, got key1=%v and keys %v", val, c.Keys())
+	}
+
+	sized := New[string, int](0, WithMaxCost(10), WithSizer(func(v int) int64 { return int64(v) }))
+	if sized.Set("key1", -3, 0) || sized.Has("key1") {
+		t.Error("expected a value sized negative not to be stored")
+	}
+	if current := c.GetStats().Cost; current != 4 {
+		t.Errorf("expected current cost 4, got %d", current)
+	}
+}
+
+func TestCostUpdateGrowsEntry(t *testing.T) {
+	c := NewCache(0, WithMaxCost(10))
+
+	c.SetWithCost("key1", "value1", 0, 3)
+	c.SetWithCost("key2", "value2", 0, 3)
+	c.SetWithCost("key3", "value3", 0, 3)
+
+	// key1 is least recently used, but growing it must evict others instead
+	c.SetWithCost("key1", "value1", 0, 7)
+	if !c.Has("key1") {
+		t.Fatal("updated entry should never evict itself")
+	}
+	if c.Has("key2") {
+		t.Error("key2 should be evicted to make room for key1")
+	}
+	if current := c.GetStats().Cost; current != 10 {
+		t.Errorf("expected current cost 10, got %d", current)
+	}
+}
+
+func TestCostCombinedWithMaxSize(t *testing.T) {
+	c := NewCache(2, WithMaxCost(100))
+
+	c.SetWithCost("key1", "value1", 0, 1)
+	c.SetWithCost("key2", "value2", 0, 1)
+	c.SetWithCost("key3", "value3", 0, 1)
+
+	if c.Size() != 2 {
+		t.Errorf("maxSize should still apply, got size %d", c.Size())
+	}
+}
+
+func TestCostTrackedAcrossDeleteAndClear(t *testing.T) {
+	c := NewCache(0, WithMaxCost(100))
+
+	c.SetWithCost("key1", "value1", 0, 10)
+	c.SetWithCost("key2", "value2", 0, 20)
+	c.Delete("key1")
+	if current := c.GetStats().Cost; current != 20 {
+		t.Errorf("expected current cost 20 after delete, got %d", current)
+	}
+
+	c.Clear()
+	if stats := c.GetStats(); stats.Cost != 0 || stats.Evictions[ReasonCapacity] != 0 {
+		t.Errorf("expected no cost and no capacity evictions, got %d, %d", stats.Cost, stats.Evictions[ReasonCapacity])
+	}
+}
+
+func TestCostWithEveryPolicy(t *testing.T) {
+	for _, policy := range allPolicies {
+		c := NewCache(0, WithMaxCost(50), WithEvictionPolicy(policy))
+		for i := 0; i < 200; i++ {
+			key := "key" + strconv.Itoa(i%40)
+			c.SetWithCost(key, i, 0, int64(i%7+1))
+			c.Get(key)
+			if current := c.GetStats().Cost; current > 50 {
+				t.Fatalf("%s: cost %d exceeds budget", policy, current)
+			}
+		}
+	}
+}
+
+func TestSizerTypeMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("mismatched sizer should panic")
+		}
+	}()
+	New[string, int](0, WithSizer(func(v string) int64 { return int64(len(v)) }))
+}
+
+func TestShardedCostBudget(t *testing.T) {
+	c := NewShardedCache(0, 4, WithMaxCost(40))
+
+	for i := 0; i < 100; i++ {
+		c.SetWithCost("key"+strconv.Itoa(i), i, 0, 2)
+	}
+
+	stats := c.GetStats()
+	if stats.Cost > 40 {
+		t.Errorf("total cost should stay within 40, got %d", stats.Cost)
+	}
+	if stats.Evictions[ReasonCapacity] == 0 {
+		t.Error("expected evictions")
+	}
+}
+
+func TestShardedUnevenCostBudget(t *testing.T) {
+	c := NewShardedCache(0, 4, WithMaxCost(10))
+	var total int64
+	for _, shard := range c.shards {
+		total += shard.maxCost
+	}
+	if total != 10 {
+		t.Errorf("expected shard budgets to add up to 10, got %d", total)
+	}
+
+	c = NewShardedCache(0, 16, WithMaxCost(3))
+	if len(c.shards) != 3 {
+		t.Errorf("expected one shard per unit of a small budget, got %d", len(c.shards))
+	}
+	for i := 0; i < 100; i++ {
+		c.SetWithCost("key"+strconv.Itoa(i), i, 0, 1)
+	}
+	if cost := c.GetStats().Cost; cost != 3 {
+		t.Errorf("expected the total cost to fill up to 3, got %d", cost)
+	}
+}
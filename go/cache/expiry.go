@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryHeap is a min-heap of entries ordered by ExpiresAt.
+// Entries that never expire are not stored in it.
+type expiryHeap[K comparable, V any] []*CacheEntry[K, V]
+
+func (h expiryHeap[K, V]) Len() int           { return len(h) }
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].ExpiresAt.Before(h[j].ExpiresAt) }
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	entry := x.(*CacheEntry[K, V])
+	entry.heapIndex = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.heapIndex = -1
+	*h = old[:n-1]
+	return entry
+}
+
+// update places the entry correctly after its ExpiresAt changed,
+// adding it to or dropping it from the heap as needed.
+func (h *expiryHeap[K, V]) update(entry *CacheEntry[K, V]) {
+	switch {
+	case entry.ExpiresAt.IsZero():
+		h.remove(entry)
+	case entry.heapIndex < 0:
+		heap.Push(h, entry)
+	default:
+		heap.Fix(h, entry.heapIndex)
+	}
+}
+
+func (h *expiryHeap[K, V]) remove(entry *CacheEntry[K, V]) {
+	if entry.heapIndex >= 0 {
+		heap.Remove(h, entry.heapIndex)
+	}
+}
+
+// removeExpired removes entries that expired before now, soonest first,
+// touching only expired entries. limit <= 0 means no limit.
+// Returns the number of entries removed.
+func (c *Cache[K, V]) removeExpired(now time.Time, limit int) int {
+	removed := 0
+	for len(c.expiry) > 0 && (limit <= 0 || removed < limit) {
+		entry := c.expiry[0]
+		if !now.After(entry.ExpiresAt) {
+			break
+		}
+		c.removeEntry(entry, ReasonExpired)
+		removed++
+	}
+	return removed
+}
+
+func (c *Cache[K, V]) startJanitor(interval time.Duration, maxPerTick int) {
+	c.stopJanitor = make(chan struct{})
+	c.janitorDone = make(chan struct{})
+
+	go func() {
+		defer close(c.janitorDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.mu.Lock()
+				c.removeExpired(c.clock.Now(), maxPerTick)
+				c.unlock()
+			case <-c.stopJanitor:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background janitor, if any, and waits for it to exit.
+// It is safe to call Close more than once, and on a cache without a janitor.
+func (c *Cache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		if c.stopJanitor == nil {
+			return
+		}
+		close(c.stopJanitor)
+		<-c.janitorDone
+	})
+}
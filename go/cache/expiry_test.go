@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpiryHeapOrder(t *testing.T) {
+	c := NewCache(10)
+
+	c.Set("key1", "value1", 300*time.Millisecond)
+	c.Set("key2", "value2", 100*time.Millisecond)
+	c.Set("key3", "value3", 0) // never expires, not in heap
+	c.Set("key4", "value4", 200*time.Millisecond)
+
+	if len(c.expiry) != 3 {
+		t.Fatalf("expected 3 entries in expiry heap, got %d", len(c.expiry))
+	}
+	if c.expiry[0].Key != "key2" {
+		t.Errorf("soonest expiry should be key2, got %s", c.expiry[0].Key)
+	}
+
+	// Moving an entry to no TTL drops it from the heap
+	c.Set("key2", "value2", 0)
+	if len(c.expiry) != 2 || c.expiry[0].Key != "key4" {
+		t.Error("key2 should leave the heap when its TTL is removed")
+	}
+
+	c.Delete("key4")
+	if len(c.expiry) != 1 || c.expiry[0].Key != "key1" {
+		t.Error("deleted entry should leave the heap")
+	}
+}
+
+func TestRemoveExpiredLimit(t *testing.T) {
+	c := NewCache(0)
+
+	for i := 0; i < 5; i++ {
+		c.Set("key"+strconv.Itoa(i), i, time.Millisecond)
+	}
+	c.Set("forever", "value", 0)
+
+	later := time.Now().Add(time.Second)
+	if removed := c.removeExpired(later, 2); removed != 2 {
+		t.Errorf("expected 2 removed with limit, got %d", removed)
+	}
+	if removed := c.removeExpired(later, 0); removed != 3 {
+		t.Errorf("expected remaining 3 removed, got %d", removed)
+	}
+	if c.Size() != 1 || !c.Has("forever") {
+		t.Error("only the non-expiring entry should remain")
+	}
+}
+
+func TestJanitorRemovesExpired(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, WithJanitor(10*time.Millisecond, 0), WithClock(clock))
+	defer c.Close()
+
+	c.Set("key1", "value1", 20*time.Millisecond)
+	c.Set("key2", "value2", 0)
+	clock.Advance(30 * time.Millisecond)
+
+	// The janitor still ticks in real time
+	time.Sleep(60 * time.Millisecond)
+
+	if c.Size() != 1 {
+		t.Errorf("janitor should have removed expired entry, size is %d", c.Size())
+	}
+	keys := c.Keys()
+	if len(keys) != 1 || keys[0] != "key2" {
+		t.Errorf("expected only key2, got %v", keys)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := NewCache(10, WithJanitor(time.Millisecond, 0))
+	c.Close()
+	c.Close() // second call must not panic
+
+	// Without a janitor Close is a no-op
+	NewCache(10).Close()
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// loadCall is a loader invocation in flight; concurrent callers for the
+// same key wait on it instead of running the loader themselves.
+type loadCall[V any] struct {
+	wg          sync.WaitGroup
+	value       V
+	err         error
+	invalidated bool // the key was set, deleted or cleared meanwhile: the result is stale
+}
+
+type loadFailure struct {
+	err       error
+	expiresAt time.Time
+}
+
+// GetOrLoad returns the value for key, calling loader to compute it on a miss
+// and storing the result with the given ttl.
+// Concurrent calls for the same missing key share a single loader call.
+// Loader errors are returned to every waiting caller and are not stored as
+// entries; with WithNegativeTTL they are remembered for a while.
+// A Set, Delete or Clear while the loader runs wins: the loaded value is
+// still returned to the waiting callers, but not stored.
+// With WithRefreshAhead, a hit close to ExpiresAt also starts a background reload.
+// Increments HitCount on a hit, like Get.
+func (c *Cache[K, V]) GetOrLoad(key K, ttl time.Duration, loader func() (V, error)) (V, error) {
+	c.mu.Lock()
+
+	now := c.clock.Now()
+	if entry, exists := c.entries[key]; exists {
+		if entry.ExpiresAt.IsZero() || !now.After(entry.ExpiresAt) {
+			c.stats.Hits++
+			entry.HitCount++
+			c.policy.Access(entry)
+			value := entry.Value
+
+			if c.shouldRefresh(entry, now) {
+				call := c.startLoad(key)
+				go c.finishLoad(key, ttl, loader, call)
+			}
+
+			c.unlock()
+			return value, nil
+		}
+		c.removeEntry(entry, ReasonExpired)
+	}
+
+	c.stats.Misses++
+	if failure, exists := c.failures[key]; exists {
+		if now.Before(failure.expiresAt) {
+			c.unlock()
+			var zero V
+			return zero, failure.err
+		}
+		delete(c.failures, key)
+	}
+
+	if call, exists := c.loads[key]; exists {
+		c.unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+
+	call := c.startLoad(key)
+	c.unlock()
+
+	c.finishLoad(key, ttl, loader, call)
+	return call.value, call.err
+}
+
+// shouldRefresh reports whether a live entry is close enough to expiring
+// to be reloaded in the background, and no reload is already running.
+func (c *Cache[K, V]) shouldRefresh(entry *CacheEntry[K, V], now time.Time) bool {
+	if c.refreshAhead <= 0 || entry.ExpiresAt.IsZero() {
+		return false
+	}
+	if _, loading := c.loads[entry.Key]; loading {
+		return false
+	}
+	return entry.ExpiresAt.Sub(now) <= c.refreshAhead
+}
+
+// startLoad registers a new in-flight load for key. Callers must hold c.mu.
+func (c *Cache[K, V]) startLoad(key K) *loadCall[V] {
+	call := &loadCall[V]{}
+	call.wg.Add(1)
+	c.loads[key] = call
+	return call
+}
+
+// invalidateLoad keeps an in-flight load of key from storing its result,
+// and lets the next miss start a fresh one. Callers must hold c.mu.
+func (c *Cache[K, V]) invalidateLoad(key K) {
+	if call, exists := c.loads[key]; exists {
+		call.invalidated = true
+		delete(c.loads, key)
+	}
+}
+
+// finishLoad runs loader outside the lock, stores its outcome and wakes up waiters.
+// A panicking loader is reported as an error so waiters are never left hanging.
+func (c *Cache[K, V]) finishLoad(key K, ttl time.Duration, loader func() (V, error), call *loadCall[V]) {
+	defer call.wg.Done()
+
+	start := c.clock.Now()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.err = fmt.Errorf("cache: loader panicked: %v", r)
+			}
+		}()
+		call.value, call.err = loader()
+	}()
+	elapsed := c.clock.Now().Sub(start)
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.stats.Loads++
+	c.stats.TotalLoadTime += elapsed
+	if call.err != nil {
+		c.stats.LoadErrors++
+	}
+	if call.invalidated {
+		return
+	}
+	delete(c.loads, key)
+	if call.err == nil {
+		delete(c.failures, key)
+		c.set(key, call.value, ttl, c.costOf(call.value))
+		return
+	}
+	if c.negativeTTL > 0 {
+		c.failures[key] = &loadFailure{err: call.err, expiresAt: c.clock.Now().Add(c.negativeTTL)}
+	}
+}
@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadMissAndHit(t *testing.T) {
+	c := NewCache(10)
+	calls := 0
+	loader := func() (interface{}, error) {
+		calls++
+		return "loaded", nil
+	}
+
+	val, err := c.GetOrLoad("key1", 0, loader)
+	if err != nil || val != "loaded" {
+		t.Fatalf("expected loaded, got %v, %v", val, err)
+	}
+
+	val, err = c.GetOrLoad("key1", 0, loader)
+	if err != nil || val != "loaded" {
+		t.Fatalf("expected loaded, got %v, %v", val, err)
+	}
+	if calls != 1 {
+		t.Errorf("loader should run once, ran %d times", calls)
+	}
+
+	// The loaded value is a regular entry
+	if val, ok := c.Get("key1"); !ok || val != "loaded" {
+		t.Error("loaded value should be stored in the cache")
+	}
+	if c.entries["key1"].HitCount != 2 {
+		t.Errorf("expected 2 hits, got %d", c.entries["key1"].HitCount)
+	}
+}
+
+func TestGetOrLoadDeduplicatesConcurrentLoads(t *testing.T) {
+	c := NewCache(10)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func() (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "loaded", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad("key1", 0, loader)
+		}(i)
+	}
+
+	// Each caller counts a miss under the lock that then finds the load in
+	// flight: once all have, they are all waiting on it
+	waitFor(t, func() bool { return c.GetStats().Misses == len(results) })
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("loader should run once, ran %d times", calls.Load())
+	}
+	for i, result := range results {
+		if result != "loaded" {
+			t.Errorf("caller %d got %v", i, result)
+		}
+	}
+}
+
+func TestGetOrLoadLosesToConcurrentChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Cache[string, interface{}])
+		want   interface{} // nil: no entry
+	}{
+		{"delete", func(c *Cache[string, interface{}]) { c.Delete("key1") }, nil},
+		{"set", func(c *Cache[string, interface{}]) { c.Set("key1", "fresh", 0) }, "fresh"},
+		{"clear", func(c *Cache[string, interface{}]) { c.Clear() }, nil},
+	}
+	for _, tt := range tests {
+		clock := NewFakeClock(time.Now())
+		c := NewCache(10, WithClock(clock))
+		c.Set("key1", "old", time.Minute)
+		clock.Advance(2 * time.Minute) // expire it, so GetOrLoad reloads
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan interface{})
+		go func() {
+			val, _ := c.GetOrLoad("key1", 0, func() (interface{}, error) {
+				close(started)
+				<-release
+				return "stale", nil
+			})
+			done <- val
+		}()
+
+		<-started
+		tt.change(c)
+		close(release)
+		if val := <-done; val != "stale" {
+			t.Errorf("%s: expected the caller to get its loaded value, got %v", tt.name, val)
+		}
+
+		val, ok := c.Get("key1")
+		if tt.want == nil && ok {
+			t.Errorf("%s: expected no entry, got %v", tt.name, val)
+		}
+		if tt.want != nil && val != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, val)
+		}
+
+		// The next miss loads afresh
+		if tt.want == nil {
+			if val, _ := c.GetOrLoad("key1", 0, func() (interface{}, error) { return "new", nil }); val != "new" {
+				t.Errorf("%s: expected a new load, got %v", tt.name, val)
+			}
+		}
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	c := NewCache(10)
+	errBoom := errors.New("boom")
+	calls := 0
+	loader := func() (interface{}, error) {
+		calls++
+		return nil, errBoom
+	}
+
+	if _, err := c.GetOrLoad("key1", 0, loader); !errors.Is(err, errBoom) {
+		t.Errorf("expected boom, got %v", err)
+	}
+	if c.Has("key1") {
+		t.Error("errors should not be stored as entries")
+	}
+
+	// Without a negative TTL every call retries
+	c.GetOrLoad("key1", 0, loader)
+	if calls != 2 {
+		t.Errorf("expected 2 loader calls, got %d", calls)
+	}
+}
+
+func TestGetOrLoadNegativeTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, WithNegativeTTL(50*time.Millisecond), WithClock(clock))
+	errBoom := errors.New("boom")
+	calls := 0
+	failing := func() (interface{}, error) {
+		calls++
+		return nil, errBoom
+	}
+
+	c.GetOrLoad("key1", 0, failing)
+	if _, err := c.GetOrLoad("key1", 0, failing); !errors.Is(err, errBoom) {
+		t.Errorf("cached error should be returned, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("loader should not rerun while the error is cached, ran %d times", calls)
+	}
+
+	clock.Advance(60 * time.Millisecond)
+
+	val, err := c.GetOrLoad("key1", 0, func() (interface{}, error) { return "recovered", nil })
+	if err != nil || val != "recovered" {
+		t.Errorf("expected recovery after negative TTL, got %v, %v", val, err)
+	}
+}
+
+func TestGetOrLoadDeleteClearsCachedError(t *testing.T) {
+	c := NewCache(10, WithNegativeTTL(time.Hour))
+
+	c.GetOrLoad("key1", 0, func() (interface{}, error) { return nil, errors.New("boom") })
+	c.Delete("key1")
+
+	val, err := c.GetOrLoad("key1", 0, func() (interface{}, error) { return "ok", nil })
+	if err != nil || val != "ok" {
+		t.Errorf("Delete should forget the cached error, got %v, %v", val, err)
+	}
+}
+
+func TestGetOrLoadPanickingLoader(t *testing.T) {
+	c := NewCache(10)
+
+	_, err := c.GetOrLoad("key1", 0, func() (interface{}, error) { panic("bad loader") })
+	if err == nil {
+		t.Fatal("panicking loader should surface as an error")
+	}
+
+	val, err := c.GetOrLoad("key1", 0, func() (interface{}, error) { return "ok", nil })
+	if err != nil || val != "ok" {
+		t.Errorf("key should be loadable after a panic, got %v, %v", val, err)
+	}
+}
+
+func TestGetOrLoadRefreshAhead(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, WithRefreshAhead(80*time.Millisecond), WithClock(clock))
+	var version atomic.Int32
+	loader := func() (interface{}, error) {
+		return version.Add(1), nil
+	}
+
+	c.GetOrLoad("key1", 100*time.Millisecond, loader)
+
+	// Outside the refresh window: plain hit
+	if val, _ := c.GetOrLoad("key1", 100*time.Millisecond, loader); val != int32(1) {
+		t.Errorf("expected version 1, got %v", val)
+	}
+
+	clock.Advance(40 * time.Millisecond)
+
+	// Inside the window: the stale value is served while a reload starts
+	if val, _ := c.GetOrLoad("key1", 100*time.Millisecond, loader); val != int32(1) {
+		t.Errorf("expected stale version 1, got %v", val)
+	}
+
+	waitForLoad(c, "key1")
+
+	if val, _ := c.Get("key1"); val != int32(2) {
+		t.Errorf("expected refreshed version 2, got %v", val)
+	}
+	if version.Load() != 2 {
+		t.Errorf("expected exactly one refresh, got %d loads", version.Load())
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForLoad blocks until the in-flight load of key, if any, has stored its result.
+func waitForLoad(c *Cache[string, interface{}], key string) {
+	c.mu.Lock()
+	call := c.loads[key]
+	c.mu.Unlock()
+	if call != nil {
+		call.wg.Wait()
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import "time"
+
+type options struct {
+	policy        PolicyKind
+	clock         Clock
+	sweepInterval time.Duration
+	maxSweep      int
+	negativeTTL   time.Duration
+	refreshAhead  time.Duration
+	maxCost       int64
+	sizer         any // func(V) int64, checked by New
+}
+
+// Option configures a Cache at construction time.
+type Option func(*options)
+
+// WithJanitor starts a background goroutine that removes expired entries
+// every interval. maxPerTick bounds how many entries a single sweep may
+// remove so a burst of expirations cannot hold the lock for long;
+// 0 means no limit. The cache must be stopped with Close.
+func WithJanitor(interval time.Duration, maxPerTick int) Option {
+	return func(o *options) {
+		o.sweepInterval = interval
+		o.maxSweep = maxPerTick
+	}
+}
+
+// WithEvictionPolicy selects how a full cache picks the entry to evict.
+// The default is PolicyLRU.
+func WithEvictionPolicy(kind PolicyKind) Option {
+	return func(o *options) {
+		o.policy = kind
+	}
+}
+
+// WithNegativeTTL makes GetOrLoad remember a loader error for ttl, so
+// repeated lookups of a failing key return the error without calling the
+// loader again. By default errors are not cached.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithRefreshAhead makes GetOrLoad serve an entry that expires within window
+// as usual while reloading it in the background (stale-while-revalidate),
+// so hot keys are refreshed before they ever miss.
+func WithRefreshAhead(window time.Duration) Option {
+	return func(o *options) {
+		o.refreshAhead = window
+	}
+}
+
+// WithMaxCost bounds the cache by the total cost of its entries rather than
+// only by their number; entries are evicted until a new entry's cost fits.
+// Costs are usually byte sizes, provided by WithSizer or SetWithCost.
+// It can be combined with a maxSize; both limits are enforced.
+func WithMaxCost(budget int64) Option {
+	return func(o *options) {
+		o.maxCost = budget
+	}
+}
+
+// WithSizer computes the cost of every value stored through Set or GetOrLoad.
+// V must match the cache's value type, otherwise New panics.
+// A value sized negative is not stored, and Set returns false.
+func WithSizer[V any](sizer func(value V) int64) Option {
+	return func(o *options) {
+		o.sizer = sizer
+	}
+}
+
+// WithClock makes the cache read the time from clock instead of the wall clock.
+// The janitor still ticks in real time but judges expiry by clock.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"container/list"
+	"maps"
+	"slices"
+)
+
+// EvictionPolicy decides which entry a full cache evicts.
+// The cache calls it while holding its lock, so implementations need no
+// synchronization of their own. A policy may use CacheEntry.element to
+// keep its per-entry bookkeeping.
+type EvictionPolicy[K comparable, V any] interface {
+	// Add starts tracking a newly inserted entry.
+	Add(entry *CacheEntry[K, V])
+	// Access records a successful Get; HitCount is already incremented.
+	Access(entry *CacheEntry[K, V])
+	// Remove stops tracking an entry that left the cache for any reason.
+	Remove(entry *CacheEntry[K, V])
+	// Victim picks the entry to evict so a new entry fits, or nil if there is none.
+	// The cache removes the victim afterwards, calling Remove.
+	Victim() *CacheEntry[K, V]
+	// Order lists the tracked entries from the next victim to the last, such
+	// that adding them to an empty policy in this order restores the same order.
+	Order() []*CacheEntry[K, V]
+}
+
+type PolicyKind string
+
+const (
+	PolicyLRU     PolicyKind = "lru"
+	PolicyLFU     PolicyKind = "lfu"
+	PolicyFIFO    PolicyKind = "fifo"
+	PolicyTinyLFU PolicyKind = "tinylfu"
+)
+
+func newEvictionPolicy[K comparable, V any](kind PolicyKind, capacity int) EvictionPolicy[K, V] {
+	switch kind {
+	case PolicyLFU:
+		return newLFUPolicy[K, V]()
+	case PolicyFIFO:
+		return &fifoPolicy[K, V]{order: list.New()}
+	case PolicyTinyLFU:
+		return newTinyLFUPolicy[K, V](capacity)
+	default:
+		return &lruPolicy[K, V]{order: list.New()}
+	}
+}
+
+// lruPolicy evicts the least recently accessed entry.
+type lruPolicy[K comparable, V any] struct {
+	order *list.List // front = least recently accessed, back = most recent
+}
+
+func (p *lruPolicy[K, V]) Add(entry *CacheEntry[K, V]) {
+	entry.element = p.order.PushBack(entry)
+}
+
+func (p *lruPolicy[K, V]) Access(entry *CacheEntry[K, V]) {
+	p.order.MoveToBack(entry.element)
+}
+
+func (p *lruPolicy[K, V]) Remove(entry *CacheEntry[K, V]) {
+	p.order.Remove(entry.element)
+	entry.element = nil
+}
+
+func (p *lruPolicy[K, V]) Victim() *CacheEntry[K, V] {
+	if front := p.order.Front(); front != nil {
+		return front.Value.(*CacheEntry[K, V])
+	}
+	return nil
+}
+
+func (p *lruPolicy[K, V]) Order() []*CacheEntry[K, V] {
+	return listEntries[K, V](p.order, nil)
+}
+
+// fifoPolicy evicts the oldest inserted entry, ignoring accesses.
+type fifoPolicy[K comparable, V any] struct {
+	order *list.List // front = oldest insert
+}
+
+func (p *fifoPolicy[K, V]) Add(entry *CacheEntry[K, V]) {
+	entry.element = p.order.PushBack(entry)
+}
+
+func (p *fifoPolicy[K, V]) Access(entry *CacheEntry[K, V]) {}
+
+func (p *fifoPolicy[K, V]) Remove(entry *CacheEntry[K, V]) {
+	p.order.Remove(entry.element)
+	entry.element = nil
+}
+
+func (p *fifoPolicy[K, V]) Victim() *CacheEntry[K, V] {
+	if front := p.order.Front(); front != nil {
+		return front.Value.(*CacheEntry[K, V])
+	}
+	return nil
+}
+
+func (p *fifoPolicy[K, V]) Order() []*CacheEntry[K, V] {
+	return listEntries[K, V](p.order, nil)
+}
+
+// listEntries appends the *CacheEntry values of l, front to back, to entries.
+func listEntries[K comparable, V any](l *list.List, entries []*CacheEntry[K, V]) []*CacheEntry[K, V] {
+	for e := l.Front(); e != nil; e = e.Next() {
+		entries = append(entries, e.Value.(*CacheEntry[K, V]))
+	}
+	return entries
+}
+
+// lfuPolicy evicts the entry with the lowest HitCount, breaking ties by
+// least recent access. Entries are kept in one list per HitCount, so Add
+// and Access are O(1); Victim is O(1) unless removals emptied the lowest
+// bucket, in which case it rescans the buckets once.
+type lfuPolicy[K comparable, V any] struct {
+	buckets  map[int]*list.List // HitCount -> entries, front = least recently accessed
+	minCount int
+}
+
+func newLFUPolicy[K comparable, V any]() *lfuPolicy[K, V] {
+	return &lfuPolicy[K, V]{buckets: make(map[int]*list.List)}
+}
+
+func (p *lfuPolicy[K, V]) push(entry *CacheEntry[K, V]) {
+	bucket, exists := p.buckets[entry.HitCount]
+	if !exists {
+		bucket = list.New()
+		p.buckets[entry.HitCount] = bucket
+	}
+	entry.element = bucket.PushBack(entry)
+}
+
+func (p *lfuPolicy[K, V]) unlink(entry *CacheEntry[K, V], count int) {
+	bucket := p.buckets[count]
+	bucket.Remove(entry.element)
+	entry.element = nil
+	if bucket.Len() == 0 {
+		delete(p.buckets, count)
+	}
+}
+
+func (p *lfuPolicy[K, V]) Add(entry *CacheEntry[K, V]) {
+	p.push(entry)
+	if len(p.buckets) == 1 || entry.HitCount < p.minCount {
+		p.minCount = entry.HitCount
+	}
+}
+
+func (p *lfuPolicy[K, V]) Access(entry *CacheEntry[K, V]) {
+	previous := entry.HitCount - 1
+	p.unlink(entry, previous)
+	p.push(entry)
+	if previous == p.minCount && p.buckets[previous] == nil {
+		p.minCount = entry.HitCount
+	}
+}
+
+func (p *lfuPolicy[K, V]) Remove(entry *CacheEntry[K, V]) {
+	p.unlink(entry, entry.HitCount)
+}
+
+func (p *lfuPolicy[K, V]) Victim() *CacheEntry[K, V] {
+	bucket, exists := p.buckets[p.minCount]
+	if !exists {
+		if len(p.buckets) == 0 {
+			return nil
+		}
+		first := true
+		for count := range p.buckets {
+			if first || count < p.minCount {
+				p.minCount = count
+				first = false
+			}
+		}
+		bucket = p.buckets[p.minCount]
+	}
+	return bucket.Front().Value.(*CacheEntry[K, V])
+}
+
+func (p *lfuPolicy[K, V]) Order() []*CacheEntry[K, V] {
+	counts := slices.Sorted(maps.Keys(p.buckets))
+	entries := []*CacheEntry[K, V]{}
+	for _, count := range counts {
+		entries = listEntries(p.buckets[count], entries)
+	}
+	return entries
+}
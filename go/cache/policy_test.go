@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+var allPolicies = []PolicyKind{PolicyLRU, PolicyLFU, PolicyFIFO, PolicyTinyLFU}
+
+// TestPolicyScenarios runs the policy-independent cache scenarios against every policy.
+func TestPolicyScenarios(t *testing.T) {
+	scenarios := map[string]func(*testing.T, ...Option){
+		"NewCache":             testNewCache,
+		"SetAndGet":            testSetAndGet,
+		"Expiration":           testExpiration,
+		"Has":                  testHas,
+		"HasExpiration":        testHasExpiration,
+		"Delete":               testDelete,
+		"SizeAndKeys":          testSizeAndKeys,
+		"Clear":                testClear,
+		"CleanupExpired":       testCleanupExpired,
+		"MaxSizeUnlimited":     testMaxSizeUnlimited,
+		"GetStats":             testGetStats,
+		"GetMostAccessed":      testGetMostAccessed,
+		"SetUpdatesExpiration": testSetUpdatesExpiration,
+		"BoundedUnderChurn":    testBoundedUnderChurn,
+	}
+
+	for _, policy := range allPolicies {
+		for name, scenario := range scenarios {
+			t.Run(string(policy)+"/"+name, func(t *testing.T) {
+				scenario(t, WithEvictionPolicy(policy))
+			})
+		}
+	}
+}
+
+func testBoundedUnderChurn(t *testing.T, opts ...Option) {
+	c := NewCache(50, opts...)
+
+	for i := 0; i < 2000; i++ {
+		key := "key" + strconv.Itoa(i%300)
+		switch i % 7 {
+		case 0:
+			c.Delete(key)
+		case 1:
+			c.Set(key, i, time.Nanosecond)
+		case 2, 3:
+			c.Get(key)
+		default:
+			c.Set(key, i, 0)
+		}
+		if c.Size() > 50 {
+			t.Fatalf("size %d exceeds max size 50 after %d operations", c.Size(), i)
+		}
+	}
+
+	c.CleanupExpired()
+	c.Clear()
+	c.Set("key1", "value1", 0)
+	if !c.Has("key1") {
+		t.Error("cache should accept entries after Clear")
+	}
+}
+
+func TestLFUEvictsLeastFrequentlyUsed(t *testing.T) {
+	c := NewCache(3, WithEvictionPolicy(PolicyLFU))
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+
+	c.Get("key1")
+	c.Get("key1")
+	c.Get("key2")
+	c.Get("key2")
+	c.Get("key3")
+
+	// key3 has the fewest hits
+	c.Set("key4", "value4", 0)
+	if c.Has("key3") {
+		t.Error("key3 should be evicted (least frequently used)")
+	}
+
+	// key4 has 0 hits, so it is next even though it is the newest
+	c.Set("key5", "value5", 0)
+	if c.Has("key4") {
+		t.Error("key4 should be evicted (least frequently used)")
+	}
+	if !c.Has("key1") || !c.Has("key2") || !c.Has("key5") {
+		t.Error("key1, key2, key5 should exist")
+	}
+}
+
+func TestLFUTiesBrokenByRecency(t *testing.T) {
+	c := NewCache(3, WithEvictionPolicy(PolicyLFU))
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+
+	c.Get("key2")
+	c.Get("key1")
+	c.Get("key3")
+
+	// All have 1 hit, key2 was accessed longest ago
+	c.Set("key4", "value4", 0)
+	c.Get("key4")
+	c.Set("key5", "value5", 0)
+
+	if c.Has("key2") {
+		t.Error("key2 should be evicted first among equal counts")
+	}
+	if c.Has("key1") {
+		t.Error("key1 should be evicted second among equal counts")
+	}
+}
+
+func TestLFUAfterDeletingLowestCount(t *testing.T) {
+	c := NewCache(2, WithEvictionPolicy(PolicyLFU))
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Get("key2")
+	c.Get("key2")
+
+	// Deleting the only 0-hit entry leaves the lowest bucket empty
+	c.Delete("key1")
+	c.Set("key3", "value3", 0)
+	c.Get("key3")
+	c.Set("key4", "value4", 0)
+
+	if c.Has("key3") {
+		t.Error("key3 should be evicted (1 hit vs 2)")
+	}
+	if !c.Has("key2") || !c.Has("key4") {
+		t.Error("key2 and key4 should exist")
+	}
+}
+
+func TestFIFOIgnoresAccess(t *testing.T) {
+	c := NewCache(3, WithEvictionPolicy(PolicyFIFO))
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+
+	// Accessing key1 does not protect it under FIFO
+	c.Get("key1")
+	c.Get("key1")
+
+	c.Set("key4", "value4", 0)
+	if c.Has("key1") {
+		t.Error("key1 should be evicted (oldest insert)")
+	}
+
+	c.Set("key5", "value5", 0)
+	if c.Has("key2") {
+		t.Error("key2 should be evicted (oldest insert)")
+	}
+	if !c.Has("key3") || !c.Has("key4") || !c.Has("key5") {
+		t.Error("key3, key4, key5 should exist")
+	}
+}
+
+func TestTinyLFUResistsScans(t *testing.T) {
+	const size = 100
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = "hot" + strconv.Itoa(i)
+	}
+
+	for _, policy := range []PolicyKind{PolicyTinyLFU, PolicyLRU} {
+		c := NewCache(size, WithEvictionPolicy(policy))
+		for _, key := range hot {
+			c.Set(key, key, 0)
+		}
+		for round := 0; round < 10; round++ {
+			for _, key := range hot {
+				c.Get(key)
+			}
+		}
+
+		// A one-off scan over many cold keys
+		for i := 0; i < 1000; i++ {
+			c.Set("cold"+strconv.Itoa(i), i, 0)
+		}
+
+		survivors := 0
+		for _, key := range hot {
+			if c.Has(key) {
+				survivors++
+			}
+		}
+
+		switch policy {
+		case PolicyTinyLFU:
+			if survivors != len(hot) {
+				t.Errorf("tinylfu should keep all %d hot keys, kept %d", len(hot), survivors)
+			}
+		case PolicyLRU:
+			if survivors != 0 {
+				t.Errorf("lru is expected to lose hot keys to the scan, kept %d", survivors)
+			}
+		}
+	}
+}
+
+func TestTinyLFUAdmitsFrequentNewcomer(t *testing.T) {
+	c := NewCache(100, WithEvictionPolicy(PolicyTinyLFU))
+
+	for i := 0; i < 100; i++ {
+		c.Set("key"+strconv.Itoa(i), i, 0)
+	}
+
+	// A new key that keeps being requested must eventually get in and stay
+	for i := 0; i < 5; i++ {
+		c.Set("popular", i, 0)
+		c.Get("popular")
+		c.Set("filler"+strconv.Itoa(i), i, 0)
+	}
+
+	if !c.Has("popular") {
+		t.Error("frequently used newcomer should be admitted into the main segment")
+	}
+	if c.Size() != 100 {
+		t.Errorf("size should stay 100, got %d", c.Size())
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch[string](64)
+
+	for i := 0; i < 5; i++ {
+		s.increment("a")
+	}
+	s.increment("b")
+
+	if got := s.estimate("a"); got < 5 {
+		t.Errorf("estimate for a should be at least 5, got %d", got)
+	}
+	if s.estimate("a") <= s.estimate("b") {
+		t.Error("a should be estimated more frequent than b")
+	}
+
+	for i := 0; i < 100; i++ {
+		s.increment("a")
+	}
+	if got := s.estimate("a"); got > sketchMaxCounter {
+		t.Errorf("counter should saturate at %d, got %d", sketchMaxCounter, got)
+	}
+
+	s.reset()
+	if got := s.estimate("a"); got > sketchMaxCounter/2 {
+		t.Errorf("reset should halve counters, got %d", got)
+	}
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"hash/maphash"
+	"io"
+	"slices"
+	"time"
+)
+
+const defaultShardCount = 16
+
+// ShardedCache is a Cache that is safe for concurrent use.
+// Keys are spread across independently locked shards, so goroutines working
+// on different shards never contend. Capacity and LRU eviction are tracked
+// per shard, which makes eviction an approximation of a global LRU.
+type ShardedCache[K comparable, V any] struct {
+	shards []*Cache[K, V]
+	seed   maphash.Seed
+}
+
+// NewSharded creates a concurrent cache split into shardCount shards.
+// maxSize is divided across shards, the first maxSize%shardCount shards
+// holding one more entry than the rest; 0 means unlimited.
+// If shardCount <= 0, a default of 16 shards is used. It is lowered to
+// maxSize if that is smaller, so that every shard holds at least one entry.
+// opts are applied to every shard, so WithJanitor runs one sweeper per shard;
+// a WithMaxCost budget is divided across shards like maxSize, and likewise
+// lowers shardCount if smaller.
+func NewSharded[K comparable, V any](maxSize, shardCount int, opts ...Option) *ShardedCache[K, V] {
+	if shardCount <= 0 {
+		shardCount = defaultShardCount
+	}
+
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if maxSize > 0 && maxSize < shardCount {
+		shardCount = maxSize
+	}
+	if o.maxCost > 0 && o.maxCost < int64(shardCount) {
+		shardCount = int(o.maxCost)
+	}
+
+	shards := make([]*Cache[K, V], shardCount)
+	for i := range shards {
+		shardOpts := opts
+		if o.maxCost > 0 {
+			shardOpts = append(slices.Clone(opts), WithMaxCost(splitEvenly(o.maxCost, shardCount, i)))
+		}
+		shards[i] = New[K, V](int(splitEvenly(int64(maxSize), shardCount, i)), shardOpts...)
+	}
+
+	return &ShardedCache[K, V]{shards: shards, seed: maphash.MakeSeed()}
+}
+
+// splitEvenly returns shard i's part of total split across n shards, the
+// remainder going one each to the first shards, so the parts add up to total.
+func splitEvenly(total int64, n, i int) int64 {
+	part := total / int64(n)
+	if int64(i) < total%int64(n) {
+		part++
+	}
+	return part
+}
+
+// NewShardedCache creates a sharded cache with string keys and untyped values.
+func NewShardedCache(maxSize, shardCount int, opts ...Option) *ShardedCache[string, interface{}] {
+	return NewSharded[string, interface{}](maxSize, shardCount, opts...)
+}
+
+func (sc *ShardedCache[K, V]) shardFor(key K) *Cache[K, V] {
+	h := maphash.Comparable(sc.seed, key)
+	return sc.shards[h%uint64(len(sc.shards))]
+}
+
+// Set adds or updates a key with a TTL (time-to-live).
+// Returns true if a new entry was added, false if an existing entry was updated.
+func (sc *ShardedCache[K, V]) Set(key K, value V, ttl time.Duration) bool {
+	return sc.shardFor(key).Set(key, value, ttl)
+}
+
+// Get retrieves a value by key.
+// Returns (zero value, false) if not found or expired.
+func (sc *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return sc.shardFor(key).Get(key)
+}
+
+// SetWithCost adds or updates a key, charging an explicit cost against its shard's budget.
+func (sc *ShardedCache[K, V]) SetWithCost(key K, value V, ttl time.Duration, cost int64) bool {
+	return sc.shardFor(key).SetWithCost(key, value, ttl, cost)
+}
+
+// GetOrLoad returns the value for key, calling loader on a miss.
+// Concurrent loads of the same key are deduplicated within its shard.
+func (sc *ShardedCache[K, V]) GetOrLoad(key K, ttl time.Duration, loader func() (V, error)) (V, error) {
+	return sc.shardFor(key).GetOrLoad(key, ttl, loader)
+}
+
+// Delete removes a key from the cache.
+// Returns true if the key existed, false otherwise.
+func (sc *ShardedCache[K, V]) Delete(key K) bool {
+	return sc.shardFor(key).Delete(key)
+}
+
+// Has checks if a key exists and is not expired.
+// Does NOT increment HitCount.
+func (sc *ShardedCache[K, V]) Has(key K) bool {
+	return sc.shardFor(key).Has(key)
+}
+
+// Size returns the number of entries across all shards (including expired ones).
+func (sc *ShardedCache[K, V]) Size() int {
+	total := 0
+	for _, s := range sc.shards {
+		total += s.Size()
+	}
+	return total
+}
+
+// Keys returns all keys across all shards (including expired ones).
+func (sc *ShardedCache[K, V]) Keys() []K {
+	keys := []K{}
+	for _, s := range sc.shards {
+		keys = append(keys, s.Keys()...)
+	}
+	return keys
+}
+
+// Clear removes all entries from every shard.
+// Returns the number of entries removed.
+func (sc *ShardedCache[K, V]) Clear() int {
+	removed := 0
+	for _, s := range sc.shards {
+		removed += s.Clear()
+	}
+	return removed
+}
+
+// CleanupExpired removes all expired entries from every shard.
+// Returns the number of entries removed.
+func (sc *ShardedCache[K, V]) CleanupExpired() int {
+	removed := 0
+	for _, s := range sc.shards {
+		removed += s.CleanupExpired()
+	}
+	return removed
+}
+
+// GetStats returns cache statistics summed over all shards.
+func (sc *ShardedCache[K, V]) GetStats() Stats {
+	total := Stats{Evictions: make(map[EvictionReason]int)}
+	for _, s := range sc.shards {
+		stats := s.GetStats()
+		total.Entries += stats.Entries
+		total.Expired += stats.Expired
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Cost += stats.Cost
+		total.EvictedCost += stats.EvictedCost
+		total.Loads += stats.Loads
+		total.LoadErrors += stats.LoadErrors
+		total.TotalLoadTime += stats.TotalLoadTime
+		for reason, count := range stats.Evictions {
+			total.Evictions[reason] += count
+		}
+	}
+
+	if lookups := total.Hits + total.Misses; lookups > 0 {
+		total.HitRatio = float64(total.Hits) / float64(lookups)
+	}
+	return total
+}
+
+// OnEvict registers an eviction hook on every shard.
+func (sc *ShardedCache[K, V]) OnEvict(hook func(key K, value V, reason EvictionReason)) {
+	for _, s := range sc.shards {
+		s.OnEvict(hook)
+	}
+}
+
+// GetMostAccessed returns the top N non-expired entries by HitCount, sorted descending.
+// The returned entries are copies, so they are safe to read while the cache keeps changing.
+func (sc *ShardedCache[K, V]) GetMostAccessed(n int) []*CacheEntry[K, V] {
+	entries := []*CacheEntry[K, V]{}
+	for _, s := range sc.shards {
+		entries = append(entries, s.GetMostAccessed(n)...)
+	}
+
+	slices.SortFunc(entries, func(a, b *CacheEntry[K, V]) int {
+		return b.HitCount - a.HitCount
+	})
+
+	if len(entries) > n {
+		return entries[:n]
+	}
+
+	return entries
+}
+
+// SaveTo writes the entries of all shards to w, shard by shard, in the
+// same format as Cache.SaveTo.
+// Each ShardedCache spreads keys across shards with its own random seed, so
+// the shards a snapshot is loaded into do not match the ones it was saved
+// from, and there is no global eviction order to restore: only each entry's
+// value, expiry, HitCount and Cost survive a restart, and keys saved from the
+// same shard keep their relative order.
+func (sc *ShardedCache[K, V]) SaveTo(w io.Writer, codec ValueCodec[V]) error {
+	entries := []CacheEntry[K, V]{}
+	for _, s := range sc.shards {
+		s.mu.Lock()
+		entries = append(entries, s.snapshotEntries()...)
+		s.mu.Unlock()
+	}
+	return writeSnapshot(w, codec, entries)
+}
+
+// LoadFrom reads a snapshot written by Cache.SaveTo or ShardedCache.SaveTo,
+// routing each entry to its shard like Cache.LoadFrom. A shard that fills up
+// evicts by the order entries are loaded in, not by recency before the
+// restart; see SaveTo. Returns the number of entries loaded.
+func (sc *ShardedCache[K, V]) LoadFrom(r io.Reader, codec ValueCodec[V]) (int, error) {
+	loaded := 0
+	err := readSnapshot(r, codec, func(entry *CacheEntry[K, V]) {
+		s := sc.shardFor(entry.Key)
+		s.mu.Lock()
+		defer s.unlock()
+
+		if s.restore(entry, s.clock.Now()) {
+			loaded++
+		}
+	})
+	return loaded, err
+}
+
+// Close stops the janitors of all shards.
+func (sc *ShardedCache[K, V]) Close() {
+	for _, s := range sc.shards {
+		s.Close()
+	}
+}
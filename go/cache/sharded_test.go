@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedSetAndGet(t *testing.T) {
+	c := NewShardedCache(100, 4)
+
+	if !c.Set("key1", "value1", 0) {
+		t.Error("should return true for new key")
+	}
+	if c.Set("key1", "value2", 0) {
+		t.Error("should return false for update")
+	}
+
+	val, ok := c.Get("key1")
+	if !ok || val != "value2" {
+		t.Errorf("expected value2, got %v", val)
+	}
+
+	if _, ok := c.Get("key999"); ok {
+		t.Error("should not find non-existent key")
+	}
+
+	if !c.Delete("key1") {
+		t.Error("should return true for existing key")
+	}
+	if c.Has("key1") {
+		t.Error("key should be deleted")
+	}
+}
+
+func TestShardedDefaultShardCount(t *testing.T) {
+	c := NewShardedCache(0, 0)
+	if len(c.shards) != defaultShardCount {
+		t.Errorf("expected %d shards, got %d", defaultShardCount, len(c.shards))
+	}
+}
+
+func TestShardedMaxSize(t *testing.T) {
+	c := NewShardedCache(8, 4)
+
+	for i := 0; i < 100; i++ {
+		c.Set("key"+strconv.Itoa(i), i, 0)
+	}
+
+	// Each shard holds at most 2 entries
+	if c.Size() > 8 {
+		t.Errorf("size should be bounded by 8, got %d", c.Size())
+	}
+}
+
+func TestShardedUnevenMaxSize(t *testing.T) {
+	tests := []struct {
+		maxSize, shardCount int
+		wantShards          int
+		wantSizes           []int
+	}{
+		{10, 4, 4, []int{3, 3, 2, 2}},
+		{10, 16, 10, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		c := NewShardedCache(tt.maxSize, tt.shardCount)
+		if len(c.shards) != tt.wantShards {
+			t.Errorf("NewShardedCache(%d, %d): expected %d shards, got %d", tt.maxSize, tt.shardCount, tt.wantShards, len(c.shards))
+			continue
+		}
+		for i, shard := range c.shards {
+			if shard.maxSize != tt.wantSizes[i] {
+				t.Errorf("NewShardedCache(%d, %d): expected shard %d to hold %d, got %d", tt.maxSize, tt.shardCount, i, tt.wantSizes[i], shard.maxSize)
+			}
+		}
+
+		for i := 0; i < 1000; i++ {
+			c.Set("key"+strconv.Itoa(i), i, 0)
+		}
+		if c.Size() != tt.maxSize {
+			t.Errorf("NewShardedCache(%d, %d): expected to fill up to %d, got %d", tt.maxSize, tt.shardCount, tt.maxSize, c.Size())
+		}
+	}
+}
+
+func TestShardedExpiration(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewShardedCache(10, 4, WithClock(clock))
+
+	c.Set("key1", "value1", 50*time.Millisecond)
+	c.Set("key2", "value2", 50*time.Millisecond)
+	c.Set("key3", "value3", 0)
+
+	clock.Advance(60 * time.Millisecond)
+
+	if expired := c.GetStats().Expired; expired != 2 {
+		t.Errorf("expected 2 expired, got %d", expired)
+	}
+	if removed := c.CleanupExpired(); removed != 2 {
+		t.Errorf("expected 2 removed, got %d", removed)
+	}
+	if c.Size() != 1 {
+		t.Errorf("expected 1 remaining, got %d", c.Size())
+	}
+}
+
+func TestShardedKeysAndClear(t *testing.T) {
+	c := NewShardedCache(0, 4)
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+
+	if len(c.Keys()) != 3 {
+		t.Errorf("expected 3 keys, got %d", len(c.Keys()))
+	}
+	if removed := c.Clear(); removed != 3 {
+		t.Errorf("expected 3 removed, got %d", removed)
+	}
+	if c.Size() != 0 {
+		t.Error("cache should be empty after clear")
+	}
+}
+
+func TestShardedGetMostAccessed(t *testing.T) {
+	c := NewShardedCache(0, 4)
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+
+	c.Get("key1")
+	c.Get("key2")
+	c.Get("key2")
+	c.Get("key2")
+	c.Get("key3")
+	c.Get("key3")
+
+	top := c.GetMostAccessed(2)
+	if len(top) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(top))
+	}
+	if top[0].Key != "key2" || top[1].Key != "key3" {
+		t.Errorf("expected key2, key3, got %s, %s", top[0].Key, top[1].Key)
+	}
+}
+
+func TestShardedTyped(t *testing.T) {
+	c := NewSharded[int, string](0, 4)
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, strconv.Itoa(i), 0)
+	}
+
+	val, ok := c.Get(42)
+	if !ok || val != "42" {
+		t.Errorf("expected 42, got %q", val)
+	}
+	if c.Size() != 100 {
+		t.Errorf("expected 100 entries, got %d", c.Size())
+	}
+}
+
+// ==================== Concurrency Tests ====================
+
+func TestShardedConcurrentSetGet(t *testing.T) {
+	c := NewShardedCache(0, 8)
+	var wg sync.WaitGroup
+
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := "key" + strconv.Itoa(g*500+i)
+				c.Set(key, i, 0)
+				if val, ok := c.Get(key); !ok || val != i {
+					t.Errorf("expected %d for %s, got %v", i, key, val)
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	if c.Size() != 16*500 {
+		t.Errorf("expected %d entries, got %d", 16*500, c.Size())
+	}
+}
+
+func TestShardedConcurrentMixedOps(t *testing.T) {
+	c := NewShardedCache(256, 8)
+	var wg sync.WaitGroup
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := "key" + strconv.Itoa(i%64)
+				switch (g + i) % 5 {
+				case 0:
+					c.Set(key, i, time.Millisecond)
+				case 1:
+					c.Get(key)
+				case 2:
+					c.Has(key)
+				case 3:
+					c.Delete(key)
+				case 4:
+					c.GetMostAccessed(3)
+					c.GetStats()
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	if c.Size() > 256 {
+		t.Errorf("size should be bounded by 256, got %d", c.Size())
+	}
+}
+
+// ==================== Benchmarks ====================
+
+func BenchmarkCacheSetGet(b *testing.B) {
+	c := NewCache(0)
+	keys := benchmarkKeys(1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		c.Set(key, i, 0)
+		c.Get(key)
+	}
+}
+
+func BenchmarkShardedCacheSetGet(b *testing.B) {
+	c := NewShardedCache(0, 0)
+	keys := benchmarkKeys(1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		c.Set(key, i, 0)
+		c.Get(key)
+	}
+}
+
+// BenchmarkCacheParallel shares a single Cache, and therefore a single lock,
+// between all goroutines, which is the baseline the sharded cache is meant to beat.
+func BenchmarkCacheParallel(b *testing.B) {
+	c := NewCache(0)
+	keys := benchmarkKeys(1024)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			c.Set(key, i, 0)
+			c.Get(key)
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedCacheParallel(b *testing.B) {
+	c := NewShardedCache(0, 0)
+	keys := benchmarkKeys(1024)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			c.Set(key, i, 0)
+			c.Get(key)
+			i++
+		}
+	})
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	return keys
+}
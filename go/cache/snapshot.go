@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const snapshotVersion = 1
+
+// ValueCodec converts cache values to and from bytes for snapshots.
+type ValueCodec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(data []byte) (V, error)
+}
+
+// GobCodec encodes values with encoding/gob. Concrete types stored in an
+// interface{} value must be registered with gob.Register, except for
+// gob's built-in basic types.
+type GobCodec[V any] struct{}
+
+func (GobCodec[V]) Encode(value V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// JSONCodec encodes values with encoding/json. Decoding into interface{}
+// yields JSON's generic types, e.g. float64 for numbers.
+type JSONCodec[V any] struct{}
+
+func (JSONCodec[V]) Encode(value V) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+type snapshotHeader struct {
+	Version int
+	Count   int
+}
+
+type snapshotEntry[K comparable] struct {
+	Key       K
+	Value     []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	HitCount  int
+	Cost      int64
+}
+
+// SaveTo writes every entry to w, in eviction order from the next victim
+// to the last, together with CreatedAt, ExpiresAt, HitCount and Cost.
+// Keys are gob-encoded; values are encoded with codec, or GobCodec if nil.
+func (c *Cache[K, V]) SaveTo(w io.Writer, codec ValueCodec[V]) error {
+	c.mu.Lock()
+	entries := c.snapshotEntries()
+	c.mu.Unlock()
+
+	return writeSnapshot(w, codec, entries)
+}
+
+// snapshotEntries copies the entries in eviction order. Callers must hold c.mu.
+func (c *Cache[K, V]) snapshotEntries() []CacheEntry[K, V] {
+	order := c.policy.Order()
+	entries := make([]CacheEntry[K, V], len(order))
+	for i, entry := range order {
+		entries[i] = *entry
+	}
+	return entries
+}
+
+// LoadFrom reads a snapshot written by SaveTo and adds its entries,
+// silently replacing existing entries with the same key. Entries that already
+// expired are skipped. Adding in the saved order restores the eviction
+// order, so a cache of the same size and policy evicts the same keys it
+// would have before the restart; a smaller cache keeps the most recent ones.
+// Returns the number of entries loaded.
+func (c *Cache[K, V]) LoadFrom(r io.Reader, codec ValueCodec[V]) (int, error) {
+	loaded := 0
+	err := readSnapshot(r, codec, func(entry *CacheEntry[K, V]) {
+		c.mu.Lock()
+		defer c.unlock()
+
+		if c.restore(entry, c.clock.Now()) {
+			loaded++
+		}
+	})
+	return loaded, err
+}
+
+// restore inserts a saved entry as-is. An entry it replaces is dropped
+// silently: it is not evicted, so OnEvict is not called and Stats do not
+// count it. Callers must hold c.mu.
+func (c *Cache[K, V]) restore(entry *CacheEntry[K, V], now time.Time) bool {
+	if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
+		return false
+	}
+	if c.maxCost > 0 && entry.Cost > c.maxCost {
+		return false
+	}
+
+	if existing, exists := c.entries[entry.Key]; exists {
+		c.unlink(existing)
+	}
+	c.evictFor(entry.Cost, true)
+	c.insert(entry)
+	return true
+}
+
+func writeSnapshot[K comparable, V any](w io.Writer, codec ValueCodec[V], entries []CacheEntry[K, V]) error {
+	if codec == nil {
+		codec = GobCodec[V]{}
+	}
+
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Count: len(entries)}); err != nil {
+		return fmt.Errorf("cache: writing snapshot header: %w", err)
+	}
+
+	for _, entry := range entries {
+		value, err := codec.Encode(entry.Value)
+		if err != nil {
+			return fmt.Errorf("cache: encoding value for key %v: %w", entry.Key, err)
+		}
+		record := snapshotEntry[K]{
+			Key:       entry.Key,
+			Value:     value,
+			CreatedAt: entry.CreatedAt,
+			ExpiresAt: entry.ExpiresAt,
+			HitCount:  entry.HitCount,
+			Cost:      entry.Cost,
+		}
+		if err := enc.Encode(&record); err != nil {
+			return fmt.Errorf("cache: writing snapshot entry: %w", err)
+		}
+	}
+	return nil
+}
+
+func readSnapshot[K comparable, V any](r io.Reader, codec ValueCodec[V], add func(*CacheEntry[K, V])) error {
+	if codec == nil {
+		codec = GobCodec[V]{}
+	}
+
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("cache: reading snapshot header: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("cache: unsupported snapshot version %d", header.Version)
+	}
+
+	for i := 0; i < header.Count; i++ {
+		var record snapshotEntry[K]
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("cache: reading snapshot entry %d of %d: %w", i+1, header.Count, err)
+		}
+		value, err := codec.Decode(record.Value)
+		if err != nil {
+			return fmt.Errorf("cache: decoding value for key %v: %w", record.Key, err)
+		}
+		add(&CacheEntry[K, V]{
+			Key:       record.Key,
+			Value:     value,
+			CreatedAt: record.CreatedAt,
+			ExpiresAt: record.ExpiresAt,
+			HitCount:  record.HitCount,
+			Cost:      record.Cost,
+			heapIndex: -1,
+		})
+	}
+	return nil
+}
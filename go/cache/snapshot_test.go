@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	c := NewCache(10)
+	c.Set("key1", "value1", 0)
+	c.Set("key2", 42, time.Hour)
+	c.Get("key1")
+	c.Get("key1")
+
+	var buf bytes.Buffer
+	if err := c.SaveTo(&buf, nil); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	restored := NewCache(10)
+	loaded, err := restored.LoadFrom(&buf, nil)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if loaded != 2 {
+		t.Errorf("expected 2 entries loaded, got %d", loaded)
+	}
+
+	original, entry := c.entries["key1"], restored.entries["key1"]
+	if entry.Value != "value1" || entry.HitCount != 2 {
+		t.Errorf("unexpected restored entry %+v", entry)
+	}
+	if !entry.CreatedAt.Equal(original.CreatedAt) {
+		t.Errorf("CreatedAt should be preserved")
+	}
+	if val, _ := restored.Get("key2"); val != 42 {
+		t.Errorf("expected 42, got %v", val)
+	}
+	if !restored.entries["key2"].ExpiresAt.Equal(c.entries["key2"].ExpiresAt) {
+		t.Error("ExpiresAt should be preserved")
+	}
+}
+
+func TestSnapshotPreservesLRUOrder(t *testing.T) {
+	c := NewCache(3)
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+	c.Get("key1")
+	c.Get("key3")
+
+	var buf bytes.Buffer
+	c.SaveTo(&buf, nil)
+	restored := NewCache(3)
+	restored.LoadFrom(&buf, nil)
+
+	// key2 was least recently accessed before the restart, so it goes first after it
+	restored.Set("key4", "value4", 0)
+	if restored.Has("key2") {
+		t.Error("key2 should be evicted first after restore")
+	}
+	restored.Set("key5", "value5", 0)
+	if restored.Has("key1") {
+		t.Error("key1 should be evicted second after restore")
+	}
+	if !restored.Has("key3") {
+		t.Error("key3 should survive")
+	}
+}
+
+func TestSnapshotPreservesLFUOrder(t *testing.T) {
+	c := NewCache(3, WithEvictionPolicy(PolicyLFU))
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+	c.Get("key1")
+	c.Get("key1")
+	c.Get("key2")
+	c.Get("key3")
+	c.Get("key3")
+
+	var buf bytes.Buffer
+	c.SaveTo(&buf, nil)
+	restored := NewCache(3, WithEvictionPolicy(PolicyLFU))
+	restored.LoadFrom(&buf, nil)
+
+	restored.Set("key4", "value4", 0)
+	if restored.Has("key2") {
+		t.Error("key2 has the fewest hits and should be evicted")
+	}
+}
+
+func TestSnapshotSkipsExpired(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, WithClock(clock))
+	c.Set("key1", "value1", 20*time.Millisecond)
+	c.Set("key2", "value2", 0)
+
+	var buf bytes.Buffer
+	c.SaveTo(&buf, nil)
+	clock.Advance(30 * time.Millisecond)
+
+	restored := NewCache(10, WithClock(clock))
+	loaded, err := restored.LoadFrom(&buf, nil)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if loaded != 1 || restored.Size() != 1 || !restored.Has("key2") {
+		t.Errorf("only key2 should be loaded, got %v", restored.Keys())
+	}
+}
+
+func TestSnapshotIntoSmallerCache(t *testing.T) {
+	c := NewCache(0)
+	for i := 0; i < 10; i++ {
+		c.Set("key"+strconv.Itoa(i), i, 0)
+	}
+
+	var buf bytes.Buffer
+	c.SaveTo(&buf, nil)
+	restored := NewCache(3)
+	restored.LoadFrom(&buf, nil)
+
+	// The most recent entries win
+	for _, key := range []string{"key7", "key8", "key9"} {
+		if !restored.Has(key) {
+			t.Errorf("%s should be kept", key)
+		}
+	}
+	if restored.Size() != 3 {
+		t.Errorf("expected 3 entries, got %d", restored.Size())
+	}
+}
+
+func TestSnapshotReplacesExistingKeys(t *testing.T) {
+	c := NewCache(10)
+	c.Set("key1", "saved", 0)
+	var buf bytes.Buffer
+	c.SaveTo(&buf, nil)
+
+	restored := NewCache(10)
+	evicted := 0
+	restored.OnEvict(func(key string, value interface{}, reason EvictionReason) { evicted++ })
+	restored.Set("key1", "stale", 0)
+	restored.LoadFrom(&buf, nil)
+
+	if val, _ := restored.Get("key1"); val != "saved" {
+		t.Errorf("expected saved, got %v", val)
+	}
+	if restored.Size() != 1 {
+		t.Errorf("expected 1 entry, got %d", restored.Size())
+	}
+	if evicted != 0 || restored.GetStats().Evictions[ReasonDeleted] != 0 {
+		t.Errorf("expected the replaced entry not to count as an eviction, got %d", evicted)
+	}
+}
+
+type snapshotUser struct {
+	Name  string
+	Score int
+}
+
+func TestSnapshotJSONCodec(t *testing.T) {
+	c := New[int, snapshotUser](10)
+	c.Set(1, snapshotUser{Name: "alice", Score: 10}, 0)
+	c.Set(2, snapshotUser{Name: "bob", Score: 20}, 0)
+
+	var buf bytes.Buffer
+	if err := c.SaveTo(&buf, JSONCodec[snapshotUser]{}); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	restored := New[int, snapshotUser](10)
+	if _, err := restored.LoadFrom(&buf, JSONCodec[snapshotUser]{}); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if user, _ := restored.Get(2); user.Name != "bob" || user.Score != 20 {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+type failingCodec struct{}
+
+func (failingCodec) Encode(value interface{}) ([]byte, error) { return nil, errors.New("boom") }
+func (failingCodec) Decode(data []byte) (interface{}, error)  { return nil, errors.New("boom") }
+
+func TestSnapshotErrors(t *testing.T) {
+	c := NewCache(10)
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+
+	if err := c.SaveTo(io.Discard, failingCodec{}); err == nil {
+		t.Error("codec errors should be returned by SaveTo")
+	}
+
+	var buf bytes.Buffer
+	c.SaveTo(&buf, nil)
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-10])
+	if _, err := NewCache(10).LoadFrom(truncated, nil); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF for a truncated snapshot, got %v", err)
+	}
+
+	var future bytes.Buffer
+	gob.NewEncoder(&future).Encode(snapshotHeader{Version: snapshotVersion + 1})
+	if _, err := NewCache(10).LoadFrom(&future, nil); err == nil {
+		t.Error("unknown snapshot versions should be rejected")
+	}
+}
+
+func TestShardedSnapshot(t *testing.T) {
+	c := NewShardedCache(0, 4)
+	for i := 0; i < 50; i++ {
+		c.Set("key"+strconv.Itoa(i), i, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := c.SaveTo(&buf, nil); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	restored := NewShardedCache(0, 8)
+	loaded, err := restored.LoadFrom(&buf, nil)
+	if err != nil || loaded != 50 {
+		t.Fatalf("expected 50 loaded, got %d, %v", loaded, err)
+	}
+	if val, _ := restored.Get("key42"); val != 42 {
+		t.Errorf("expected 42, got %v", val)
+	}
+}
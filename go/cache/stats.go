@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"maps"
+	"time"
+)
+
+type EvictionReason string
+
+const (
+	ReasonCapacity EvictionReason = "capacity" // evicted to make room under maxSize or the cost budget
+	ReasonExpired  EvictionReason = "expired"  // TTL passed; removed lazily or by a sweep
+	ReasonDeleted  EvictionReason = "deleted"  // removed by Delete
+	ReasonCleared  EvictionReason = "cleared"  // removed by Clear
+)
+
+type Stats struct {
+	Entries     int // current entries, including expired ones not yet removed
+	Expired     int // current entries that are expired but not yet removed
+	Hits        int // Get and GetOrLoad calls that found a live entry
+	Misses      int // Get and GetOrLoad calls that did not
+	HitRatio    float64
+	Cost        int64                  // total cost of current entries
+	EvictedCost int64                  // total cost of entries evicted for capacity
+	Evictions   map[EvictionReason]int // removed entries by reason
+
+	Loads         int // completed GetOrLoad loader calls, including refreshes
+	LoadErrors    int // loader calls that returned an error or panicked
+	TotalLoadTime time.Duration
+}
+
+// AverageLoadTime returns the mean loader latency, or 0 if nothing was loaded.
+func (s Stats) AverageLoadTime() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.TotalLoadTime / time.Duration(s.Loads)
+}
+
+type evictionNotice[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// OnEvict registers a hook called whenever an entry leaves the cache,
+// with the reason it was removed. Updating a key with Set is not an eviction.
+// Hooks run after the cache lock is released, on the goroutine whose call
+// removed the entry (or the janitor), so they may safely use the cache.
+func (c *Cache[K, V]) OnEvict(hook func(key K, value V, reason EvictionReason)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictHooks = append(c.evictHooks, hook)
+}
+
+// unlock releases c.mu and then delivers the OnEvict notifications
+// queued while it was held.
+func (c *Cache[K, V]) unlock() {
+	notices := c.notices
+	c.notices = nil
+	hooks := c.evictHooks
+	c.mu.Unlock()
+
+	for _, notice := range notices {
+		for _, hook := range hooks {
+			hook(notice.key, notice.value, notice.reason)
+		}
+	}
+}
+
+// GetStats returns a snapshot of cache statistics.
+// Expired counts currently expired entries without deleting them.
+func (c *Cache[K, V]) GetStats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Evictions = maps.Clone(c.stats.Evictions)
+	stats.Entries = len(c.entries)
+	stats.Cost = c.totalCost
+
+	now := c.clock.Now()
+	for _, entry := range c.entries {
+		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
+			stats.Expired++
+		}
+	}
+
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type evictionRecord struct {
+	key    string
+	value  interface{}
+	reason EvictionReason
+}
+
+func recordEvictions(c *Cache[string, interface{}]) *[]evictionRecord {
+	records := &[]evictionRecord{}
+	c.OnEvict(func(key string, value interface{}, reason EvictionReason) {
+		*records = append(*records, evictionRecord{key, value, reason})
+	})
+	return records
+}
+
+func TestOnEvictReasons(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(2, WithClock(clock))
+	records := recordEvictions(c)
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0) // evicts key1 for capacity
+	c.Delete("key2")
+	c.Set("key4", "value4", time.Millisecond)
+	clock.Advance(5 * time.Millisecond)
+	c.Get("key4") // lazily expires key4
+	c.Set("key5", "value5", 0)
+	c.Clear()
+
+	expected := []evictionRecord{
+		{"key1", "value1", ReasonCapacity},
+		{"key2", "value2", ReasonDeleted},
+		{"key4", "value4", ReasonExpired},
+	}
+	if len(*records) != 5 {
+		t.Fatalf("expected 5 evictions, got %+v", *records)
+	}
+	for i, want := range expected {
+		if (*records)[i] != want {
+			t.Errorf("eviction %d: expected %+v, got %+v", i, want, (*records)[i])
+		}
+	}
+
+	// Clear reports the remaining entries in any order
+	cleared := map[string]bool{}
+	for _, record := range (*records)[3:] {
+		if record.reason != ReasonCleared {
+			t.Errorf("expected cleared, got %s", record.reason)
+		}
+		cleared[record.key] = true
+	}
+	if !cleared["key3"] || !cleared["key5"] {
+		t.Errorf("key3 and key5 should be reported as cleared, got %v", cleared)
+	}
+}
+
+func TestOnEvictNotCalledOnUpdate(t *testing.T) {
+	c := NewCache(10)
+	records := recordEvictions(c)
+
+	c.Set("key1", "value1", 0)
+	c.Set("key1", "value2", 0)
+
+	if len(*records) != 0 {
+		t.Errorf("updating a key is not an eviction, got %+v", *records)
+	}
+}
+
+func TestOnEvictCleanupExpired(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, WithClock(clock))
+	records := recordEvictions(c)
+
+	c.Set("key1", "value1", time.Millisecond)
+	c.Set("key2", "value2", 0)
+	clock.Advance(5 * time.Millisecond)
+	c.CleanupExpired()
+
+	if len(*records) != 1 || (*records)[0].key != "key1" || (*records)[0].reason != ReasonExpired {
+		t.Errorf("expected key1 expired, got %+v", *records)
+	}
+}
+
+func TestOnEvictHookCanUseCache(t *testing.T) {
+	c := NewCache(1)
+
+	// Hooks run outside the lock, so re-entering the cache must not deadlock
+	c.OnEvict(func(key string, value interface{}, reason EvictionReason) {
+		if reason == ReasonCapacity {
+			c.Has(key)
+			c.Size()
+		}
+	})
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+
+	if !c.Has("key2") {
+		t.Error("key2 should exist")
+	}
+}
+
+func TestStatsEvictionsByReason(t *testing.T) {
+	c := NewCache(2)
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Set("key3", "value3", 0)
+	c.Delete("key3")
+	c.Clear()
+
+	stats := c.GetStats()
+	if stats.Evictions[ReasonCapacity] != 1 {
+		t.Errorf("expected 1 capacity eviction, got %d", stats.Evictions[ReasonCapacity])
+	}
+	if stats.Evictions[ReasonDeleted] != 1 {
+		t.Errorf("expected 1 deletion, got %d", stats.Evictions[ReasonDeleted])
+	}
+	if stats.Evictions[ReasonCleared] != 1 {
+		t.Errorf("expected 1 cleared, got %d", stats.Evictions[ReasonCleared])
+	}
+
+	// The snapshot must not alias live counters
+	stats.Evictions[ReasonDeleted] = 100
+	if c.GetStats().Evictions[ReasonDeleted] != 1 {
+		t.Error("GetStats should return a copy of the eviction counters")
+	}
+}
+
+func TestStatsLoads(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(10, WithClock(clock))
+
+	c.GetOrLoad("key1", 0, func() (interface{}, error) {
+		clock.Advance(10 * time.Millisecond)
+		return "value1", nil
+	})
+	c.GetOrLoad("key1", 0, func() (interface{}, error) { return "unused", nil })
+	c.GetOrLoad("key2", 0, func() (interface{}, error) { return nil, errors.New("boom") })
+
+	stats := c.GetStats()
+	if stats.Loads != 2 || stats.LoadErrors != 1 {
+		t.Errorf("expected 2 loads and 1 error, got %d and %d", stats.Loads, stats.LoadErrors)
+	}
+	if stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("expected 1 hit and 2 misses, got %d and %d", stats.Hits, stats.Misses)
+	}
+	if stats.TotalLoadTime != 10*time.Millisecond || stats.AverageLoadTime() != 5*time.Millisecond {
+		t.Errorf("expected 10ms total and 5ms average load time, got %v and %v", stats.TotalLoadTime, stats.AverageLoadTime())
+	}
+	if (Stats{}).AverageLoadTime() != 0 {
+		t.Error("average load time without loads should be 0")
+	}
+}
+
+func TestShardedStatsAndOnEvict(t *testing.T) {
+	c := NewShardedCache(0, 4)
+	evicted := 0
+	c.OnEvict(func(key string, value interface{}, reason EvictionReason) {
+		evicted++
+	})
+
+	c.Set("key1", "value1", 0)
+	c.Set("key2", "value2", 0)
+	c.Get("key1")
+	c.Get("key999")
+	c.Delete("key2")
+
+	stats := c.GetStats()
+	if stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.Evictions[ReasonDeleted] != 1 || evicted != 1 {
+		t.Errorf("expected 1 deletion reported, got %d and %d", stats.Evictions[ReasonDeleted], evicted)
+	}
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+type tinyLFUSegment int
+
+const (
+	segmentWindow tinyLFUSegment = iota
+	segmentProbation
+	segmentProtected
+)
+
+type tinyLFUNode[K comparable, V any] struct {
+	entry   *CacheEntry[K, V]
+	segment tinyLFUSegment
+}
+
+// tinyLFUPolicy implements W-TinyLFU: new entries land in a small LRU window,
+// and an entry leaving the window is only admitted into the main segmented
+// LRU if a frequency sketch says it is used more often than the entry it
+// would push out. This keeps one-off scans from flushing frequently used keys.
+type tinyLFUPolicy[K comparable, V any] struct {
+	window    *list.List
+	probation *list.List
+	protected *list.List
+
+	windowCap    int
+	protectedCap int
+
+	sketch *countMinSketch[K]
+}
+
+func newTinyLFUPolicy[K comparable, V any](capacity int) *tinyLFUPolicy[K, V] {
+	windowCap := max(1, capacity/100)
+	mainCap := max(0, capacity-windowCap)
+
+	return &tinyLFUPolicy[K, V]{
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		windowCap:    windowCap,
+		protectedCap: mainCap * 80 / 100,
+		sketch:       newCountMinSketch[K](capacity),
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) segmentList(segment tinyLFUSegment) *list.List {
+	switch segment {
+	case segmentProbation:
+		return p.probation
+	case segmentProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) moveTo(entry *CacheEntry[K, V], segment tinyLFUSegment) {
+	node := entry.element.Value.(*tinyLFUNode[K, V])
+	p.segmentList(node.segment).Remove(entry.element)
+	node.segment = segment
+	entry.element = p.segmentList(segment).PushBack(node)
+}
+
+func (p *tinyLFUPolicy[K, V]) Add(entry *CacheEntry[K, V]) {
+	p.sketch.increment(entry.Key)
+	entry.element = p.window.PushBack(&tinyLFUNode[K, V]{entry: entry, segment: segmentWindow})
+
+	// While the cache is still filling up, window overflow goes straight to probation
+	if p.window.Len() > p.windowCap {
+		oldest := p.window.Front().Value.(*tinyLFUNode[K, V]).entry
+		p.moveTo(oldest, segmentProbation)
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) Access(entry *CacheEntry[K, V]) {
+	p.sketch.increment(entry.Key)
+
+	node := entry.element.Value.(*tinyLFUNode[K, V])
+	switch node.segment {
+	case segmentWindow, segmentProtected:
+		p.segmentList(node.segment).MoveToBack(entry.element)
+	case segmentProbation:
+		p.moveTo(entry, segmentProtected)
+		if p.protected.Len() > p.protectedCap {
+			demoted := p.protected.Front().Value.(*tinyLFUNode[K, V]).entry
+			p.moveTo(demoted, segmentProbation)
+		}
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) Remove(entry *CacheEntry[K, V]) {
+	node := entry.element.Value.(*tinyLFUNode[K, V])
+	p.segmentList(node.segment).Remove(entry.element)
+	entry.element = nil
+}
+
+func (p *tinyLFUPolicy[K, V]) Victim() *CacheEntry[K, V] {
+	mainVictim := p.probation.Front()
+	if mainVictim == nil {
+		mainVictim = p.protected.Front()
+	}
+
+	candidate := p.window.Front()
+	if candidate == nil || p.window.Len() < p.windowCap {
+		// The window has room for the new entry, so make room in main
+		if mainVictim == nil {
+			mainVictim = candidate
+		}
+		if mainVictim == nil {
+			return nil
+		}
+		return mainVictim.Value.(*tinyLFUNode[K, V]).entry
+	}
+
+	candidateEntry := candidate.Value.(*tinyLFUNode[K, V]).entry
+	if mainVictim == nil {
+		return candidateEntry
+	}
+
+	// Admission: the window's oldest entry replaces main's victim only if it is used more often
+	victimEntry := mainVictim.Value.(*tinyLFUNode[K, V]).entry
+	if p.sketch.estimate(candidateEntry.Key) > p.sketch.estimate(victimEntry.Key) {
+		p.moveTo(candidateEntry, segmentProbation)
+		return victimEntry
+	}
+	return candidateEntry
+}
+
+// Order lists main before window, as window entries are the newest.
+// Segments and sketch counts are not part of the order, so re-adding
+// entries restarts them in the window and probation.
+func (p *tinyLFUPolicy[K, V]) Order() []*CacheEntry[K, V] {
+	entries := []*CacheEntry[K, V]{}
+	for _, l := range []*list.List{p.probation, p.protected, p.window} {
+		for e := l.Front(); e != nil; e = e.Next() {
+			entries = append(entries, e.Value.(*tinyLFUNode[K, V]).entry)
+		}
+	}
+	return entries
+}
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15
+)
+
+// countMinSketch estimates access frequency with small saturating counters.
+// Rows are 8 counters per cached entry wide, which keeps collisions rare for
+// the keys competing for admission. All counters are halved every sampleSize
+// increments so old popularity fades.
+type countMinSketch[K comparable] struct {
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	seed       maphash.Seed
+	additions  int
+	sampleSize int
+}
+
+func newCountMinSketch[K comparable](capacity int) *countMinSketch[K] {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	width := 16
+	for width < 8*capacity {
+		width <<= 1
+	}
+
+	s := &countMinSketch[K]{
+		mask:       uint64(width - 1),
+		seed:       maphash.MakeSeed(),
+		sampleSize: 10 * capacity,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// indexes derives one counter per row from the key hash using splitmix64,
+// so two keys sharing a counter in one row are unlikely to share the others.
+func (s *countMinSketch[K]) indexes(key K) [sketchDepth]uint64 {
+	h := maphash.Comparable(s.seed, key)
+	var idx [sketchDepth]uint64
+	for i := range idx {
+		h += 0x9e3779b97f4a7c15
+		x := h
+		x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+		x = (x ^ x>>27) * 0x94d049bb133111eb
+		idx[i] = (x ^ x>>31) & s.mask
+	}
+	return idx
+}
+
+func (s *countMinSketch[K]) increment(key K) {
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < sketchMaxCounter {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch[K]) estimate(key K) uint8 {
+	minimum := uint8(sketchMaxCounter)
+	for i, idx := range s.indexes(key) {
+		minimum = min(minimum, s.rows[i][idx])
+	}
+	return minimum
+}
+
+func (s *countMinSketch[K]) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,246 @@
+package emitter
+
+import (
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what EmitAsync does with an event for a listener
+// whose queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes EmitAsync wait until the listener catches up.
+	// A listener must then not EmitAsync an event that is queued for itself.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest queued event to make room.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the event being emitted.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// EmitAsync queues an event for every registered listener whose filter
+// accepts it and returns without waiting for them. Each listener has its own bounded queue and
+// goroutine, so a slow listener only delays itself; events reach a listener
+// in the order they were queued. When a queue is full the emitter's
+// OverflowPolicy applies. Listener failures go to the WithErrorHandler handler.
+// Priorities only order the queueing, and ErrStopPropagation has no effect.
+// Returns the number of listeners the event was queued for, which is also
+// what the event log records. After Close, events are logged but not delivered.
+// With WithOutbox, the event is written to the outbox first and not queued
+// at all if that fails; durable listeners acknowledge it once handled, and
+// dead-letter it if they fail it or their queue drops it.
+func (e *EventEmitter) EmitAsync(eventName string, data interface{}) int {
+	start := time.Now()
+	e.metrics.emitted(eventName)
+	offset, err := e.persist(eventName, data)
+	if err != nil {
+		e.logEvent(EventLog{EventName: eventName, Data: data})
+		return 0
+	}
+
+	e.mu.Lock()
+	candidates := e.matchListeners(eventName)
+	e.mu.Unlock()
+
+	// Durable listeners acknowledge or dead-letter from their worker
+	accepted := []*Listener{}
+	pending := map[string]bool{}
+	for _, l := range candidates {
+		if ok, _ := e.accepts(l, eventName, data); ok {
+			accepted = append(accepted, l)
+			if l.durable != "" {
+				pending[l.durable] = true
+			}
+		}
+	}
+
+	e.mu.Lock()
+	listeners := []*Listener{}
+	last := []bool{}
+	if !e.closed {
+		for _, l := range accepted {
+			if e.reserve(l) {
+				e.startQueue(l)
+				listeners = append(listeners, l)
+				last = append(last, l.removed)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	queued := 0
+	for i, l := range listeners {
+		ok, dropped := l.queue.push(queuedEvent{eventName: eventName, data: data, offset: offset})
+		if ok {
+			queued++
+		}
+		if dropped != nil {
+			e.deadLetter(l, dropped.eventName, dropped.offset, dropped.data)
+		}
+		if last[i] {
+			l.queue.stop()
+		}
+	}
+	e.settle(offset, pending)
+	e.metrics.dispatched(eventName, time.Since(start))
+
+	e.logEvent(EventLog{
+		EventName: eventName,
+		Data:      data,
+		Listeners: queued,
+	})
+
+	return queued
+}
+
+// Close stops asynchronous delivery: EmitAsync no longer queues events, and
+// Close waits until every listener has handled the events already queued.
+// Emit keeps working synchronously. Close may be called more than once.
+func (e *EventEmitter) Close() {
+	e.mu.Lock()
+	e.closed = true
+	for _, listeners := range e.listeners {
+		for _, l := range listeners {
+			l.stopQueue()
+		}
+	}
+	e.mu.Unlock()
+
+	e.workers.Wait()
+}
+
+// startQueue gives l its queue and worker goroutine if it has none yet.
+// Callers must hold e.mu.
+func (e *EventEmitter) startQueue(l *Listener) {
+	if l.queue != nil {
+		return
+	}
+	l.queue = newListenerQueue(e.queueSize, e.overflow)
+
+	e.workers.Add(1)
+	go func(l *Listener, queue *listenerQueue) {
+		defer e.workers.Done()
+		for {
+			event, ok := queue.pop()
+			if !ok {
+				return
+			}
+			if failure, _ := e.call(l, event.eventName, event.data); failure == nil {
+				e.acknowledge(l, event.eventName, event.offset)
+			} else {
+				e.deadLetter(l, event.eventName, event.offset, event.data)
+			}
+		}
+	}(l, l.queue)
+}
+
+// stopQueue lets the listener's worker exit once its queue is drained.
+// Callers must hold e.mu.
+func (l *Listener) stopQueue() {
+	if l.queue != nil {
+		l.queue.stop()
+	}
+}
+
+// detachAll marks listeners that were unregistered together as removed.
+// Callers must hold e.mu.
+func detachAll(listeners []*Listener) {
+	for _, l := range listeners {
+		l.stopQueue()
+		l.markRemoved()
+	}
+}
+
+type queuedEvent struct {
+	eventName string
+	data      interface{}
+	offset    uint64 // outbox offset, 0 without an outbox
+}
+
+// listenerQueue is a bounded FIFO of events for one listener.
+type listenerQueue struct {
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	events   []queuedEvent
+	size     int
+	overflow OverflowPolicy
+	stopped  bool
+}
+
+func newListenerQueue(size int, overflow OverflowPolicy) *listenerQueue {
+	q := &listenerQueue{
+		events:   make([]queuedEvent, 0, size),
+		size:     size,
+		overflow: overflow,
+	}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+	return q
+}
+
+// push queues an event, applying the overflow policy if the queue is full.
+// Returns false if the event was not queued, and the event the overflow
+// policy dropped, if any: this one or the oldest queued.
+func (q *listenerQueue) push(event queuedEvent) (queued bool, dropped *queuedEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.overflow == OverflowBlock && len(q.events) >= q.size && !q.stopped {
+		q.notFull.Wait()
+	}
+	if q.stopped {
+		return false, nil
+	}
+
+	if len(q.events) >= q.size {
+		if q.overflow != OverflowDropOldest {
+			return false, &event
+		}
+		oldest := q.events[0]
+		dropped = &oldest
+		q.events[0] = queuedEvent{}
+		q.events = q.events[1:]
+	}
+	q.events = append(q.events, event)
+	q.notEmpty.Signal()
+	return true, dropped
+}
+
+// pop waits for the next event. ok is false once the queue is stopped and drained.
+func (q *listenerQueue) pop() (event queuedEvent, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.events) == 0 && !q.stopped {
+		q.notEmpty.Wait()
+	}
+	if len(q.events) == 0 {
+		return queuedEvent{}, false
+	}
+
+	event = q.events[0]
+	q.events[0] = queuedEvent{}
+	q.events = q.events[1:]
+	q.notFull.Signal()
+	return event, true
+}
+
+// len returns the number of queued events.
+func (q *listenerQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.events)
+}
+
+// stop refuses further events; queued ones are still handed out by pop.
+func (q *listenerQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.stopped = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
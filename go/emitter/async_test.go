@@ -0,0 +1,277 @@
+package emitter
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedListener records what it receives; its first call blocks until the
+// gate is opened, so tests can fill its queue deterministically.
+type gatedListener struct {
+	mu       sync.Mutex
+	received []interface{}
+	started  chan struct{}
+	gate     chan struct{}
+	once     sync.Once
+}
+
+func newGatedListener() *gatedListener {
+	return &gatedListener{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (g *gatedListener) callback(data interface{}) {
+	g.once.Do(func() {
+		close(g.started)
+		<-g.gate
+	})
+	g.mu.Lock()
+	g.received = append(g.received, data)
+	g.mu.Unlock()
+}
+
+func (g *gatedListener) values() []interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.received)
+}
+
+func TestEmitAsyncDeliversInOrder(t *testing.T) {
+	e := NewEventEmitter()
+
+	var mu sync.Mutex
+	received := []interface{}{}
+	e.On("event", func(data interface{}) {
+		mu.Lock()
+		received = append(received, data)
+		mu.Unlock()
+	})
+
+	for i := 0; i < 100; i++ {
+		if count := e.EmitAsync("event", i); count != 1 {
+			t.Fatalf("expected event queued for 1 listener, got %d", count)
+		}
+	}
+	e.Close()
+
+	if len(received) != 100 {
+		t.Fatalf("Close should drain all 100 events, got %d", len(received))
+	}
+	for i, data := range received {
+		if data != i {
+			t.Fatalf("events should arrive in emit order, got %v at %d", data, i)
+		}
+	}
+
+	logs := e.GetEventLogs()
+	if len(logs) != 100 || logs[0].Listeners != 1 {
+		t.Errorf("each async emit should be logged, got %d logs", len(logs))
+	}
+}
+
+func TestEmitAsyncSlowListenerDoesNotStallOthers(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(4), WithOverflow(OverflowDropNewest))
+	slow := newGatedListener()
+	e.On("event", slow.callback)
+
+	fast := make(chan interface{}, 10)
+	e.On("event", func(data interface{}) { fast <- data })
+
+	// The slow listener blocks on the first event; drop-newest keeps the emitter going
+	for i := 0; i < 10; i++ {
+		e.EmitAsync("event", i)
+		select {
+		case <-fast:
+		case <-time.After(time.Second):
+			t.Fatal("fast listener should not wait for the slow one")
+		}
+	}
+
+	close(slow.gate)
+	e.Close()
+}
+
+func TestEmitAsyncOverflowDropNewest(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(2), WithOverflow(OverflowDropNewest))
+	g := newGatedListener()
+	e.On("event", g.callback)
+
+	e.EmitAsync("event", 1)
+	<-g.started // 1 is being handled, the queue is empty
+
+	e.EmitAsync("event", 2)
+	e.EmitAsync("event", 3)
+	if count := e.EmitAsync("event", 4); count != 0 {
+		t.Errorf("event 4 should be dropped, queued for %d listeners", count)
+	}
+
+	close(g.gate)
+	e.Close()
+
+	if got := g.values(); !slices.Equal(got, []interface{}{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", got)
+	}
+}
+
+func TestEmitAsyncOverflowDropOldest(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(2), WithOverflow(OverflowDropOldest))
+	g := newGatedListener()
+	e.On("event", g.callback)
+
+	e.EmitAsync("event", 1)
+	<-g.started
+
+	for i := 2; i <= 5; i++ {
+		if count := e.EmitAsync("event", i); count != 1 {
+			t.Errorf("event %d should be queued, queued for %d listeners", i, count)
+		}
+	}
+
+	close(g.gate)
+	e.Close()
+
+	if got := g.values(); !slices.Equal(got, []interface{}{1, 4, 5}) {
+		t.Errorf("expected [1 4 5], got %v", got)
+	}
+}
+
+func TestEmitAsyncOverflowBlock(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(1))
+	g := newGatedListener()
+	e.On("event", g.callback)
+
+	e.EmitAsync("event", 1)
+	<-g.started
+	e.EmitAsync("event", 2)
+
+	done := make(chan struct{})
+	go func() {
+		e.EmitAsync("event", 3)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("EmitAsync should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(g.gate)
+	<-done
+	e.Close()
+
+	if got := g.values(); !slices.Equal(got, []interface{}{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", got)
+	}
+}
+
+func TestEmitAsyncOnce(t *testing.T) {
+	e := NewEventEmitter()
+
+	var mu sync.Mutex
+	calls := 0
+	e.Once("event", func(data interface{}) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	})
+
+	e.EmitAsync("event", nil)
+	e.EmitAsync("event", nil)
+	e.Close()
+
+	if calls != 1 {
+		t.Errorf("once listener should be called once, got %d", calls)
+	}
+	if e.ListenerCount("event") != 0 {
+		t.Error("once listener should be removed after EmitAsync")
+	}
+}
+
+func TestOffKeepsQueuedEvents(t *testing.T) {
+	e := NewEventEmitter()
+	g := newGatedListener()
+	id := e.On("event", g.callback)
+
+	e.EmitAsync("event", 1)
+	<-g.started
+	e.EmitAsync("event", 2)
+	e.Off(id)
+	if count := e.EmitAsync("event", 3); count != 0 {
+		t.Errorf("removed listener should not get new events, queued for %d", count)
+	}
+
+	close(g.gate)
+	e.Close()
+
+	if got := g.values(); !slices.Equal(got, []interface{}{1, 2}) {
+		t.Errorf("events queued before Off should be delivered, got %v", got)
+	}
+}
+
+func TestEmitAsyncAfterClose(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := 0
+	e.On("event", func(data interface{}) { calls++ })
+	e.Close()
+	e.Close()
+
+	if count := e.EmitAsync("event", nil); count != 0 {
+		t.Errorf("EmitAsync after Close should deliver nothing, got %d", count)
+	}
+	if len(e.GetEventLogs()) != 1 {
+		t.Error("EmitAsync after Close should still be logged")
+	}
+
+	if count := e.Emit("event", nil).Delivered; count != 1 || calls != 1 {
+		t.Error("Emit should keep working after Close")
+	}
+}
+
+func TestConcurrentEmitAndRegister(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(8), WithOverflow(OverflowDropOldest))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				id := e.On("event", func(data interface{}) {})
+				e.Once("event", func(data interface{}) {})
+				if j%2 == 0 {
+					e.Emit("event", j)
+				} else {
+					e.EmitAsync("event", j)
+				}
+				e.ListenerCount("event")
+				e.GetListeners("event")
+				e.Off(id)
+			}
+		}(i)
+	}
+	wg.Wait()
+	e.Close()
+
+	if len(e.GetEventLogs()) != 8*200 {
+		t.Errorf("expected %d logs, got %d", 8*200, len(e.GetEventLogs()))
+	}
+}
+
+func TestCallbackCanUseEmitter(t *testing.T) {
+	e := NewEventEmitter()
+
+	e.On("first", func(data interface{}) {
+		e.On("second", func(data interface{}) {})
+		e.Emit("second", data)
+	})
+
+	if count := e.Emit("first", nil).Delivered; count != 1 {
+		t.Errorf("expected 1 listener, got %d", count)
+	}
+	if e.ListenerCount("second") != 1 || len(e.GetEventLogsByName("second")) != 1 {
+		t.Error("callback should be able to register and emit")
+	}
+}
@@ -0,0 +1,107 @@
+package emitter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OnContext registers a listener like On that is removed when ctx is done,
+// so a listener tied to a request cannot outlive it. Events emitted after
+// ctx is done never reach the callback, even before the removal happens.
+// The listener can also be removed earlier with Off.
+// Returns the listener ID.
+func (e *EventEmitter) OnContext(ctx context.Context, eventName string, callback func(data interface{}), opts ...ListenerOption) string {
+	l := &Listener{EventName: eventName, Callback: callback, ctx: ctx}
+	id := e.addListener(l, opts)
+
+	stop := context.AfterFunc(ctx, func() { e.Off(id) })
+	e.mu.Lock()
+	if l.removed {
+		stop()
+	} else {
+		l.release = func() { stop() }
+	}
+	e.mu.Unlock()
+
+	return id
+}
+
+// Subscribe returns a channel that receives every event matching eventName,
+// which may be a pattern, so consumers can select over it. The EventLog
+// values carry EventName, Data and the time of delivery; Seq, Listeners
+// and Errors are not known yet and left zero.
+// The channel buffers as many events as an EmitAsync queue; when it is full
+// the emitter's OverflowPolicy applies, blocking the emitting goroutine by
+// default. cancel unsubscribes and closes the channel; so does removing the
+// subscription with OffAll. cancel may be called more than once.
+func (e *EventEmitter) Subscribe(eventName string) (events <-chan EventLog, cancel func()) {
+	sub := &subscription{
+		ch:       make(chan EventLog, e.queueSize),
+		done:     make(chan struct{}),
+		overflow: e.overflow,
+	}
+	l := &Listener{EventName: eventName, receive: sub.send, release: sub.close}
+	id := e.addListener(l, nil)
+
+	return sub.ch, func() {
+		e.Off(id)
+		sub.close()
+	}
+}
+
+// subscription feeds a Subscribe channel. mu keeps sends and closing apart.
+type subscription struct {
+	mu        sync.Mutex
+	ch        chan EventLog
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    bool
+	overflow  OverflowPolicy
+}
+
+func (s *subscription) send(eventName string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	entry := EventLog{EventName: eventName, Data: data, Timestamp: time.Now()}
+
+	switch s.overflow {
+	case OverflowDropNewest:
+		select {
+		case s.ch <- entry:
+		default:
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.ch <- entry:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+			default:
+			}
+		}
+	default:
+		// done unblocks a send waiting on a full channel when the subscription ends
+		select {
+		case s.ch <- entry:
+		case <-s.done:
+		}
+	}
+}
+
+func (s *subscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
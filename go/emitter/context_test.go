@@ -0,0 +1,159 @@
+package emitter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOnContextRemovedOnCancel(t *testing.T) {
+	e := NewEventEmitter()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	e.OnContext(ctx, "event", func(data interface{}) { calls++ })
+
+	e.Emit("event", nil)
+	cancel()
+
+	// No calls after cancel, even before the listener is removed
+	e.Emit("event", nil)
+	if calls != 1 {
+		t.Errorf("listener should not be called after cancel, got %d calls", calls)
+	}
+	waitFor(t, func() bool { return e.ListenerCount("event") == 0 })
+}
+
+func TestOnContextAlreadyDone(t *testing.T) {
+	e := NewEventEmitter()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	e.OnContext(ctx, "event", func(data interface{}) { calls++ })
+	e.Emit("event", nil)
+
+	if calls != 0 {
+		t.Error("listener with a done context should never be called")
+	}
+	waitFor(t, func() bool { return e.ListenerCount("event") == 0 })
+}
+
+func TestOnContextOffBeforeCancel(t *testing.T) {
+	e := NewEventEmitter()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := e.OnContext(ctx, "event", func(data interface{}) {}, WithPriority(1))
+	if !e.Off(id) {
+		t.Error("OnContext listener should be removable with Off")
+	}
+	if e.ListenerCount("") != 0 {
+		t.Error("listener should be gone after Off")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	e := NewEventEmitter()
+
+	events, cancel := e.Subscribe("order.*")
+	e.Emit("order.created", "O-1")
+	e.Emit("user.created", "U-1")
+	e.EmitAsync("order.shipped", "O-1")
+
+	for _, want := range []string{"order.created", "order.shipped"} {
+		select {
+		case entry := <-events:
+			if entry.EventName != want || entry.Data != "O-1" {
+				t.Errorf("expected %s, got %+v", want, entry)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s on the channel", want)
+		}
+	}
+
+	if e.ListenerCount("order.created") != 1 {
+		t.Error("subscription should count as a listener")
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("channel should be closed after cancel")
+	}
+	if e.ListenerCount("") != 0 {
+		t.Error("subscription should be removed after cancel")
+	}
+	e.Emit("order.created", "O-2")
+	e.Close()
+}
+
+func TestSubscribeClosedByOffAll(t *testing.T) {
+	e := NewEventEmitter()
+
+	events, cancel := e.Subscribe("event")
+	defer cancel()
+
+	e.OffAll("")
+	if _, ok := <-events; ok {
+		t.Error("channel should be closed when the subscription is removed")
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(2), WithOverflow(OverflowDropOldest))
+	events, cancel := e.Subscribe("event")
+
+	for i := 1; i <= 4; i++ {
+		e.Emit("event", i)
+	}
+	cancel()
+
+	received := []interface{}{}
+	for entry := range events {
+		received = append(received, entry.Data)
+	}
+	if len(received) != 2 || received[0] != 3 || received[1] != 4 {
+		t.Errorf("drop-oldest should keep the newest events, got %v", received)
+	}
+}
+
+func TestSubscribeCancelUnblocksEmit(t *testing.T) {
+	e := NewEventEmitter(WithQueueSize(1))
+	events, cancel := e.Subscribe("event")
+
+	e.Emit("event", 1)
+	done := make(chan struct{})
+	go func() {
+		e.Emit("event", 2) // blocks: the channel is full
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Emit should block on a full subscription by default")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cancel should unblock the pending Emit")
+	}
+	if entry := <-events; entry.Data != 1 {
+		t.Errorf("buffered event should still be readable, got %v", entry.Data)
+	}
+}
@@ -0,0 +1,118 @@
+package emitter
+
+import "fmt"
+
+// WithOutbox makes Emit and EmitAsync write every event to outbox before
+// dispatching it, so listeners registered with OnDurable get it at least
+// once, even if the process dies first. An event that cannot be written is
+// not dispatched. The emitter does not close the outbox; close it after the
+// emitter.
+func WithOutbox(outbox *Outbox) Option {
+	return func(o *options) {
+		o.outbox = outbox
+	}
+}
+
+// OnDurable registers a listener, like OnErr, whose progress survives
+// restarts. name identifies it across processes and must be unique. An
+// event counts as handled once handler returns nil, or when the listener
+// skips it through its pattern or filter; events it failed, or that were
+// dropped from its EmitAsync queue, become its dead letters.
+//
+// A name seen before first gets its dead letters and then every event after
+// its acknowledged offset redelivered, in order, on the caller's goroutine
+// before OnDurable returns; a dead letter it fails again stays one. Events
+// emitted meanwhile may be delivered live before that backlog is done, and
+// an event may be delivered twice. A new name starts with events emitted
+// from now on.
+// Requires WithOutbox. Returns the listener ID.
+func (e *EventEmitter) OnDurable(name, eventName string, handler func(data interface{}) error, opts ...ListenerOption) (string, error) {
+	if e.outbox == nil {
+		return "", fmt.Errorf("emitter: OnDurable %q needs an emitter created WithOutbox", name)
+	}
+
+	if err := e.outbox.claim(name); err != nil {
+		return "", err
+	}
+
+	// Register before reading the backlog: every event is then either in
+	// the backlog or delivered live, possibly both.
+	l := &Listener{EventName: eventName, Handler: handler, durable: name}
+	l.release = func() { e.outbox.release(name) }
+	id := e.addListener(l, opts)
+	acked, last := e.outbox.activate(name)
+
+	pattern := newTopicTrie()
+	pattern.insert(eventName)
+	handled := func(offset uint64, recordName string, data interface{}) bool {
+		if len(pattern.match(recordName)) == 0 {
+			return true
+		}
+		accepted, _ := e.accepts(l, recordName, data)
+		if !accepted {
+			return true
+		}
+		failure, _ := e.call(l, recordName, data)
+		return failure == nil
+	}
+	err := e.outbox.deadLetters(name, handled)
+	if err == nil {
+		err = e.outbox.read(acked, last, func(offset uint64, recordName string, data interface{}) {
+			if handled(offset, recordName, data) {
+				e.acknowledge(l, recordName, offset)
+			} else {
+				e.deadLetter(l, recordName, offset, data)
+			}
+		})
+	}
+	if err != nil {
+		e.Off(id)
+		return "", err
+	}
+
+	return id, nil
+}
+
+// acknowledge records that a durable listener handled the event at offset.
+// A failure to persist it is passed to the error handler; the event is
+// then redelivered after a restart.
+func (e *EventEmitter) acknowledge(l *Listener, eventName string, offset uint64) {
+	if l.durable == "" || offset == 0 {
+		return
+	}
+	if err := e.outbox.ack(l.durable, offset); err != nil {
+		e.fail(l, eventName, err)
+	}
+}
+
+// deadLetter records that a durable listener failed the event at offset, so
+// it is redelivered when the listener registers again. A failure to persist
+// it is passed to the error handler; the event is then redelivered after a
+// restart with the rest of the backlog.
+func (e *EventEmitter) deadLetter(l *Listener, eventName string, offset uint64, data interface{}) {
+	if l.durable == "" || offset == 0 {
+		return
+	}
+	if err := e.outbox.deadLetter(l.durable, offset, eventName, data); err != nil {
+		e.fail(l, eventName, err)
+	}
+}
+
+// persist writes an event to the outbox, if there is one. Returns its
+// offset, or 0 without an outbox.
+func (e *EventEmitter) persist(eventName string, data interface{}) (uint64, error) {
+	if e.outbox == nil {
+		return 0, nil
+	}
+	return e.outbox.append(eventName, data)
+}
+
+// settle acknowledges offset for every durable listener not in unsettled:
+// those that failed it dead-lettered it already.
+func (e *EventEmitter) settle(offset uint64, unsettled map[string]bool) {
+	if offset == 0 {
+		return
+	}
+	// Losing this only causes redelivery, which at-least-once allows.
+	e.outbox.settle(offset, unsettled)
+}
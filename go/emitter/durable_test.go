@@ -0,0 +1,300 @@
+package emitter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// durableRecorder is a durable handler that records what it was given and
+// fails the events named in fail.
+type durableRecorder struct {
+	mu   sync.Mutex
+	got  []interface{}
+	fail map[interface{}]bool
+}
+
+func (r *durableRecorder) handle(data interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, data)
+	if r.fail[data] {
+		return errors.New("handler failed")
+	}
+	return nil
+}
+
+func (r *durableRecorder) values() []interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]interface{}{}, r.got...)
+}
+
+// restart simulates a process restart: the outbox in dir is reopened
+// without closing the old one, as after a crash. Acknowledgements are
+// written at once, so the crash loses none.
+func restart(t *testing.T, dir string) (*EventEmitter, *Outbox) {
+	t.Helper()
+	o := openOutbox(t, dir, WithAckInterval(0))
+	t.Cleanup(func() { o.Close() })
+	return NewEventEmitter(WithOutbox(o)), o
+}
+
+func TestDurableRedeliversFailedEvents(t *testing.T) {
+	dir := t.TempDir()
+	e, o := restart(t, dir)
+	first := &durableRecorder{fail: map[interface{}]bool{2: true}}
+	if _, err := e.OnDurable("billing", "order", first.handle); err != nil {
+		t.Fatalf("OnDurable: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		e.Emit("order", i)
+	}
+
+	// The failed event was dead-lettered, so the offset moved past it
+	if acked, _ := o.Acked("billing"); acked != 3 {
+		t.Errorf("expected acked 3, got %d", acked)
+	}
+	if dead := o.DeadLetters("billing"); !reflect.DeepEqual(dead, []uint64{2}) {
+		t.Errorf("expected offset 2 dead-lettered, got %v", dead)
+	}
+
+	// Only the failed event comes back; failing it again keeps it
+	e, _ = restart(t, dir)
+	again := &durableRecorder{fail: map[interface{}]bool{2: true}}
+	e.OnDurable("billing", "order", again.handle)
+	if got := again.values(); !reflect.DeepEqual(got, []interface{}{2}) {
+		t.Errorf("expected event 2 redelivered, got %v", got)
+	}
+
+	e, _ = restart(t, dir)
+	second := &durableRecorder{}
+	e.OnDurable("billing", "order", second.handle)
+	if got := second.values(); !reflect.DeepEqual(got, []interface{}{2}) {
+		t.Errorf("expected event 2 redelivered again, got %v", got)
+	}
+
+	e, _ = restart(t, dir)
+	third := &durableRecorder{}
+	e.OnDurable("billing", "order", third.handle)
+	if got := third.values(); len(got) != 0 {
+		t.Errorf("expected nothing redelivered once handled, got %v", got)
+	}
+}
+
+func TestDurableRedeliversEverythingAfterLostAcks(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := restart(t, dir)
+	e.OnDurable("billing", "order", (&durableRecorder{}).handle)
+	for i := 1; i <= 3; i++ {
+		e.Emit("order", i)
+	}
+
+	// Truncated by a crash mid-write: starting up beats losing nothing
+	for _, content := range []string{"", `{"billing":`} {
+		if err := os.WriteFile(filepath.Join(dir, consumersFile), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		e, _ = restart(t, dir)
+		rec := &durableRecorder{}
+		if _, err := e.OnDurable("billing", "order", rec.handle); err != nil {
+			t.Fatalf("OnDurable: %v", err)
+		}
+		if got := rec.values(); !reflect.DeepEqual(got, []interface{}{1, 2, 3}) {
+			t.Errorf("%q: expected every event redelivered, got %v", content, got)
+		}
+	}
+}
+
+func TestDurableKeepsBacklogAfterLostAcks(t *testing.T) {
+	dir := t.TempDir()
+	reopen := func(opts ...OutboxOption) (*EventEmitter, *Outbox) {
+		o := openOutbox(t, dir, append(opts, WithAckInterval(0))...)
+		t.Cleanup(func() { o.Close() })
+		return NewEventEmitter(WithOutbox(o)), o
+	}
+
+	e, o := reopen()
+	e.OnDurable("billing", "order", (&durableRecorder{}).handle)
+	for i := 1; i <= 3; i++ {
+		e.Emit("order", i)
+	}
+	o.Close()
+	if err := os.WriteFile(filepath.Join(dir, consumersFile), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every event starts a segment, before billing registers again
+	e, o = reopen(WithSegmentSize(1))
+	for i := 4; i <= 5; i++ {
+		e.Emit("order", i)
+	}
+	rec := &durableRecorder{}
+	if _, err := e.OnDurable("billing", "order", rec.handle); err != nil {
+		t.Fatalf("OnDurable: %v", err)
+	}
+	if got := rec.values(); !reflect.DeepEqual(got, []interface{}{1, 2, 3, 4, 5}) {
+		t.Errorf("expected every event redelivered, got %v", got)
+	}
+
+	e.Emit("order", 6)
+	if o.Segments() != 4 {
+		t.Errorf("expected no automatic compaction, got %d segments", o.Segments())
+	}
+	if removed, _ := o.Compact(); removed != 3 {
+		t.Errorf("expected an explicit Compact to remove the acknowledged segments, removed %d", removed)
+	}
+}
+
+func TestDurableCatchesUpOnMissedEvents(t *testing.T) {
+	dir := t.TempDir()
+	e, o := restart(t, dir)
+	id, _ := e.OnDurable("audit", "user.*", (&durableRecorder{}).handle)
+	e.Off(id)
+
+	// Emitted while the listener is gone, here or in another process
+	e.Emit("user.created", "alice")
+	e.Emit("order.created", "book")
+	e.Emit("user.deleted", "bob")
+	if acked, _ := o.Acked("audit"); acked != 0 {
+		t.Errorf("expected nothing acknowledged while away, got %d", acked)
+	}
+
+	e, o = restart(t, dir)
+	rec := &durableRecorder{}
+	e.OnDurable("audit", "user.*", rec.handle)
+	if got := rec.values(); !reflect.DeepEqual(got, []interface{}{"alice", "bob"}) {
+		t.Errorf("expected the missed user events, got %v", got)
+	}
+	if acked, _ := o.Acked("audit"); acked != 3 {
+		t.Errorf("expected non-matching events acknowledged too, got %d", acked)
+	}
+
+	e.Emit("user.created", "carol")
+	if got := rec.values(); len(got) != 3 || got[2] != "carol" {
+		t.Errorf("expected live delivery after catching up, got %v", got)
+	}
+}
+
+func TestDurableDeadLettersDroppedEvents(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir, WithAckInterval(0))
+	defer o.Close()
+	e := NewEventEmitter(WithOutbox(o), WithQueueSize(1), WithOverflow(OverflowDropNewest))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e.OnDurable("slow", "job", func(data interface{}) error {
+		if data == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+
+	e.EmitAsync("job", 1)
+	<-started
+	e.EmitAsync("job", 2) // queued
+	e.EmitAsync("job", 3) // dropped: the queue is full
+	close(release)
+	e.Close()
+
+	if acked, _ := o.Acked("slow"); acked != 3 {
+		t.Errorf("expected acked 3 past the dropped event, got %d", acked)
+	}
+	if dead := o.DeadLetters("slow"); !reflect.DeepEqual(dead, []uint64{3}) {
+		t.Errorf("expected the dropped event dead-lettered, got %v", dead)
+	}
+}
+
+func TestDurableNewListenerStartsAtEnd(t *testing.T) {
+	e, _ := restart(t, t.TempDir())
+	e.Emit("event", 1)
+
+	rec := &durableRecorder{}
+	e.OnDurable("late", "event", rec.handle)
+	e.Emit("event", 2)
+	if got := rec.values(); !reflect.DeepEqual(got, []interface{}{2}) {
+		t.Errorf("expected only events emitted after registering, got %v", got)
+	}
+}
+
+func TestDurableEmitAsync(t *testing.T) {
+	dir := t.TempDir()
+	e, o := restart(t, dir)
+	rec := &durableRecorder{fail: map[interface{}]bool{2: true}}
+	e.OnDurable("worker", "job", rec.handle)
+	// A plain listener settles nothing for the durable one
+	e.On("job", func(data interface{}) {})
+
+	for i := 1; i <= 3; i++ {
+		e.EmitAsync("job", i)
+	}
+	e.Close()
+	if acked, _ := o.Acked("worker"); acked != 3 {
+		t.Errorf("expected acked 3 past the dead-lettered event, got %d", acked)
+	}
+
+	e, _ = restart(t, dir)
+	again := &durableRecorder{}
+	e.OnDurable("worker", "job", again.handle)
+	if got := again.values(); !reflect.DeepEqual(got, []interface{}{2}) {
+		t.Errorf("expected event 2 redelivered, got %v", got)
+	}
+}
+
+func TestDurableFilteredEventsAreAcknowledged(t *testing.T) {
+	e, o := restart(t, t.TempDir())
+	rec := &durableRecorder{}
+	e.OnDurable("evens", "n", rec.handle, WithFilter(func(data interface{}) bool {
+		return data.(int)%2 == 0
+	}))
+	for i := 1; i <= 4; i++ {
+		e.Emit("n", i)
+	}
+	if acked, _ := o.Acked("evens"); acked != 4 {
+		t.Errorf("expected every event acknowledged, got %d", acked)
+	}
+}
+
+func TestOnDurableDuplicateName(t *testing.T) {
+	e, _ := restart(t, t.TempDir())
+	id, err := e.OnDurable("name", "event", (&durableRecorder{}).handle)
+	if err != nil {
+		t.Fatalf("OnDurable: %v", err)
+	}
+	if _, err := e.OnDurable("name", "other", (&durableRecorder{}).handle); err == nil {
+		t.Error("expected an error for a name already registered")
+	}
+
+	// The name is free again once the listener is removed
+	e.Off(id)
+	if _, err := e.OnDurable("name", "event", (&durableRecorder{}).handle); err != nil {
+		t.Errorf("expected the name to be reusable after Off, got %v", err)
+	}
+}
+
+func TestOnDurableRequiresOutbox(t *testing.T) {
+	e := NewEventEmitter()
+	if _, err := e.OnDurable("name", "event", (&durableRecorder{}).handle); err == nil {
+		t.Error("expected an error without WithOutbox")
+	}
+}
+
+func TestEmitPersistError(t *testing.T) {
+	e, o := restart(t, t.TempDir())
+	calls := 0
+	e.On("event", func(data interface{}) { calls++ })
+	o.Close()
+
+	result := e.Emit("event", nil)
+	if !errors.Is(result.PersistError, ErrOutboxClosed) || !errors.Is(result.Err(), ErrOutboxClosed) {
+		t.Errorf("expected ErrOutboxClosed, got %v", result.Err())
+	}
+	if calls != 0 || result.Delivered != 0 {
+		t.Errorf("expected no delivery when the event cannot be persisted, got %d calls", calls)
+	}
+}
@@ -1,8 +1,13 @@
 package emitter
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"slices"
+	"sync"
 	"time"
 )
 
@@ -10,82 +15,151 @@ type Listener struct {
 	ID        string
 	EventName string
 	Callback  func(data interface{})
-	Once      bool // if true, remove after first call
+	Handler   func(data interface{}) error // set instead of Callback by OnErr
+	Once      bool                         // if true, remove after first call
 	CreatedAt time.Time
+
+	Priority int                         // higher priorities are called first, see WithPriority
+	Filter   func(data interface{}) bool // events it rejects are skipped, see WithFilter
+	MaxCalls int                         // removed after this many calls, 0 means unlimited
+
+	seq     int                                      // registration order across all event names
+	calls   int                                      // calls so far, counted against MaxCalls
+	removed bool                                     // no longer registered; guarded by EventEmitter.mu
+	queue   *listenerQueue                           // started by the first EmitAsync that reaches this listener
+	ctx     context.Context                          // set by OnContext; no calls once it is done
+	receive func(eventName string, data interface{}) // set by Subscribe instead of Callback
+	release func()                                   // frees what the listener holds once it is removed
+	durable string                                   // OnDurable name, acknowledged in the outbox
+	stats   listenerStats                            // guarded by the emitter's metrics lock
 }
 
 type EventLog struct {
+	Seq       uint64 // position in the log, counting from 1
 	EventName string
 	Data      interface{}
-	Timestamp time.Time
-	Listeners int // number of listeners that received this event
+	Timestamp time.Time       // when the event was logged, after synchronous delivery
+	Listeners int             // number of listeners that received this event
+	Errors    []ListenerError // listeners that failed during a synchronous Emit
 }
 
+// EventEmitter is safe for concurrent use. Callbacks run without the
+// emitter's lock held, so they may register, remove or emit themselves.
+//
+// Listeners may register for a pattern instead of a single event name; see
+// the wildcard syntax in trie.go.
 type EventEmitter struct {
-	listeners   map[string][]*Listener
-	eventLogs   []EventLog
+	listeners   map[string][]*Listener // by the name or pattern they registered for
+	topics      *topicTrie
+	eventLogs   *eventLog
+	taps        []*logTap
 	listenerSeq int
-	// Add fields as needed
+	mu          sync.Mutex
+
+	outbox       *Outbox
+	metrics      *metrics
+	queueSize    int
+	overflow     OverflowPolicy
+	errorHandler func(ListenerError)
+	workers      sync.WaitGroup
+	closed       bool
 }
 
-func NewEventEmitter() *EventEmitter {
+func NewEventEmitter(opts ...Option) *EventEmitter {
+	o := options{
+		queueSize:      defaultQueueSize,
+		overflow:       OverflowBlock,
+		logCapacity:    defaultLogCapacity,
+		latencyBuckets: defaultLatencyBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return &EventEmitter{
-		listeners:   make(map[string][]*Listener),
-		eventLogs:   make([]EventLog, 0),
-		listenerSeq: 0,
+		listeners:    make(map[string][]*Listener),
+		topics:       newTopicTrie(),
+		eventLogs:    newEventLog(o.logCapacity, o.logRetention),
+		listenerSeq:  0,
+		queueSize:    o.queueSize,
+		overflow:     o.overflow,
+		errorHandler: o.errorHandler,
+		outbox:       o.outbox,
+		metrics:      newMetrics(o),
 	}
 }
 
-// On registers a listener for an event.
+// On registers a listener for an event, or for every event matching a
+// pattern such as "order.*" or "order.**".
 // Returns the listener ID (format: "L-{sequential number}").
 // The same callback can be registered multiple times.
-func (e *EventEmitter) On(eventName string, callback func(data interface{})) string {
-	e.listenerSeq++
-	listenerID := fmt.Sprintf("L-%d", e.listenerSeq)
-
-	newListener := &Listener{
-		ID:        listenerID,
-		EventName: eventName,
-		Callback:  callback,
-		Once:      false,
-		CreatedAt: time.Now(),
-	}
-	e.listeners[eventName] = append(e.listeners[eventName], newListener)
-
-	return listenerID
+// Options such as WithPriority, WithFilter and WithMaxCalls tune when it is called.
+func (e *EventEmitter) On(eventName string, callback func(data interface{}), opts ...ListenerOption) string {
+	return e.addListener(&Listener{EventName: eventName, Callback: callback}, opts)
 }
 
 // Once registers a listener that will be removed after it fires once.
+// It is the same as On with WithMaxCalls(1).
 // Returns the listener ID.
-func (e *EventEmitter) Once(eventName string, callback func(data interface{})) string {
-	e.listenerSeq++
-	listenerID := fmt.Sprintf("L-%d", e.listenerSeq)
+func (e *EventEmitter) Once(eventName string, callback func(data interface{}), opts ...ListenerOption) string {
+	return e.addListener(&Listener{EventName: eventName, Callback: callback}, append(opts, WithMaxCalls(1)))
+}
 
-	newListener := &Listener{
-		ID:        listenerID,
-		EventName: eventName,
-		Callback:  callback,
-		Once:      true,
-		CreatedAt: time.Now(),
+// OnErr registers a listener that can fail. A returned error is reported in
+// the EmitResult and event log of a synchronous Emit, and passed to the
+// WithErrorHandler handler. Returning ErrStopPropagation instead keeps
+// Emit from calling the remaining listeners.
+// Returns the listener ID.
+func (e *EventEmitter) OnErr(eventName string, handler func(data interface{}) error, opts ...ListenerOption) string {
+	return e.addListener(&Listener{EventName: eventName, Handler: handler}, opts)
+}
+
+// OnceErr is OnErr for a listener that will be removed after it fires once.
+func (e *EventEmitter) OnceErr(eventName string, handler func(data interface{}) error, opts ...ListenerOption) string {
+	return e.addListener(&Listener{EventName: eventName, Handler: handler}, append(opts, WithMaxCalls(1)))
+}
+
+// addListener applies opts to newListener, assigns its ID and registers it.
+func (e *EventEmitter) addListener(newListener *Listener, opts []ListenerOption) string {
+	for _, opt := range opts {
+		opt(newListener)
 	}
-	e.listeners[eventName] = append(e.listeners[eventName], newListener)
+	newListener.Once = newListener.MaxCalls == 1
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-	return listenerID
+	e.listenerSeq++
+	newListener.ID = formatListenerID(e.listenerSeq)
+	newListener.CreatedAt = time.Now()
+	newListener.seq = e.listenerSeq
+
+	eventName := newListener.EventName
+	e.setListeners(eventName, append(e.listeners[eventName], newListener))
+
+	return newListener.ID
+}
+
+func formatListenerID(seq int) string {
+	return fmt.Sprintf("L-%d", seq)
 }
 
 // Off removes a listener by ID.
 // Returns true if the listener was found and removed.
+// Events already queued for the listener by EmitAsync are still delivered.
 func (e *EventEmitter) Off(listenerID string) bool {
-	for key, listeners := range e.listeners {
-		for i, listener := range listeners {
-			if listener.ID == listenerID {
-				listeners = append(listeners[:i], listeners[i+1:]...)
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-				if len(listeners) == 0 {
-					delete(e.listeners, key)
-				} else {
-					e.listeners[key] = listeners
-				}
+	if e.removeTap(listenerID) {
+		return true
+	}
+
+	for _, listeners := range e.listeners {
+		for _, listener := range listeners {
+			if listener.ID == listenerID {
+				listener.stopQueue()
+				e.removeListener(listener)
 
 				return true
 			}
@@ -95,15 +169,39 @@ func (e *EventEmitter) Off(listenerID string) bool {
 	return false
 }
 
-// OffAll removes all listeners for an event.
+// removeListener unregisters l. Callers must hold e.mu.
+func (e *EventEmitter) removeListener(l *Listener) {
+	listeners := e.listeners[l.EventName]
+	if i := slices.Index(listeners, l); i >= 0 {
+		e.setListeners(l.EventName, slices.Delete(listeners, i, i+1))
+	}
+	l.markRemoved()
+}
+
+// markRemoved flags l as unregistered and runs its release hook.
+// Callers must hold e.mu.
+func (l *Listener) markRemoved() {
+	l.removed = true
+	if l.release != nil {
+		l.release()
+		l.release = nil
+	}
+}
+
+// OffAll removes all listeners registered for an event name or pattern;
+// listeners of other patterns that match it are kept.
 // Returns the number of listeners removed.
 // If eventName is empty, removes ALL listeners for ALL events.
 func (e *EventEmitter) OffAll(eventName string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	removed := 0
 	if eventName == "" {
 		for event, listeners := range e.listeners {
 			removed += len(listeners)
-			delete(e.listeners, event)
+			detachAll(listeners)
+			e.setListeners(event, nil)
 		}
 
 		return removed
@@ -114,52 +212,202 @@ func (e *EventEmitter) OffAll(eventName string) int {
 	}
 
 	removed = len(e.listeners[eventName])
+	detachAll(e.listeners[eventName])
 
-	delete(e.listeners, eventName)
+	e.setListeners(eventName, nil)
 
 	return removed
 }
 
 // Emit triggers an event with the given data.
-// Calls all registered listeners for that event.
-// Returns the number of listeners called.
-// Logs the event in eventLogs.
+// Calls all listeners registered for that event or a matching pattern,
+// highest priority first and in registration order within a priority,
+// skipping those whose filter rejects data.
+// Returns how many listeners were called and which of them failed; a
+// panicking listener or filter is recovered and reported as a *PanicError,
+// and the remaining listeners still run unless one returns ErrStopPropagation.
+// Logs the event, including the failures, once all listeners returned.
+// With WithOutbox, the event is written to the outbox first; if that fails
+// it is not dispatched and PersistError says why.
 // "Once" listeners should be removed after being called.
-func (e *EventEmitter) Emit(eventName string, data interface{}) int {
-	listeners, exists := e.listeners[eventName]
-	if !exists {
-		e.eventLogs = append(e.eventLogs, EventLog{
-			EventName: eventName,
-			Data:      data,
-			Timestamp: time.Now(),
-			Listeners: 0,
-		})
-
-		return 0
+// Callbacks run on the caller's goroutine; see EmitAsync for queued delivery.
+func (e *EventEmitter) Emit(eventName string, data interface{}) EmitResult {
+	start := time.Now()
+	e.metrics.emitted(eventName)
+	result := EmitResult{}
+	offset, err := e.persist(eventName, data)
+	if err != nil {
+		result.PersistError = err
+		e.logEvent(EventLog{EventName: eventName, Data: data})
+		return result
 	}
 
-	remaining := []*Listener{}
+	e.mu.Lock()
+	listeners := e.matchListeners(eventName)
+	e.mu.Unlock()
+
 	for _, l := range listeners {
-		l.Callback(data)
-		if !l.Once {
-			remaining = append(remaining, l)
+		accepted, failure := e.accepts(l, eventName, data)
+		if failure != nil {
+			result.Errors = append(result.Errors, *failure)
+		}
+		if !accepted {
+			continue
+		}
+
+		e.mu.Lock()
+		reserved := e.reserve(l)
+		if reserved && l.removed {
+			l.stopQueue()
+		}
+		e.mu.Unlock()
+		if !reserved {
+			continue
+		}
+
+		result.Delivered++
+		failure, stop := e.call(l, eventName, data)
+		if failure != nil {
+			result.Errors = append(result.Errors, *failure)
+			e.deadLetter(l, eventName, offset, data)
+		}
+		if stop {
+			break
 		}
 	}
-	e.listeners[eventName] = remaining
+	e.settle(offset, nil)
+	e.metrics.dispatched(eventName, time.Since(start))
 
-	e.eventLogs = append(e.eventLogs, EventLog{
+	e.logEvent(EventLog{
 		EventName: eventName,
 		Data:      data,
-		Timestamp: time.Now(),
-		Listeners: len(listeners),
+		Listeners: result.Delivered,
+		Errors:    result.Errors,
 	})
 
-	return len(listeners)
+	return result
+}
+
+// reserve counts a call of l against its MaxCalls, removing l when that was
+// the last one, so concurrent emits never exceed it. Returns false if l was
+// removed in the meantime. Callers must hold e.mu.
+func (e *EventEmitter) reserve(l *Listener) bool {
+	if l.removed {
+		return false
+	}
+	l.calls++
+	if l.MaxCalls > 0 && l.calls >= l.MaxCalls {
+		e.removeListener(l)
+	}
+	return true
+}
+
+// accepts runs the listener's filter, if any. A panicking filter rejects
+// the event and is reported like a failing listener.
+func (e *EventEmitter) accepts(l *Listener, eventName string, data interface{}) (accepted bool, failure *ListenerError) {
+	if l.ctx != nil && l.ctx.Err() != nil {
+		return false, nil
+	}
+	if l.Filter == nil {
+		return true, nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			accepted = false
+			failure = e.fail(l, eventName, &PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+	return l.Filter(data), nil
+}
+
+// call runs a listener, turning a returned error or a panic into a
+// ListenerError. stop reports whether the listener returned ErrStopPropagation.
+func (e *EventEmitter) call(l *Listener, eventName string, data interface{}) (failure *ListenerError, stop bool) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			failure = e.fail(l, eventName, &PanicError{Value: r, Stack: debug.Stack()})
+		}
+		e.metrics.called(l, eventName, time.Since(start))
+	}()
+
+	switch {
+	case l.receive != nil:
+		l.receive(eventName, data)
+		return nil, false
+	case l.Handler == nil:
+		l.Callback(data)
+		return nil, false
+	}
+
+	err := l.Handler(data)
+	if errors.Is(err, ErrStopPropagation) {
+		return nil, true
+	}
+	if err != nil {
+		return e.fail(l, eventName, err), false
+	}
+	return nil, false
+}
+
+// fail wraps a listener failure and passes it to the error handler.
+func (e *EventEmitter) fail(l *Listener, eventName string, err error) *ListenerError {
+	failure := &ListenerError{ListenerID: l.ID, EventName: eventName, Err: err}
+	e.metrics.failed(l, eventName)
+	if e.errorHandler != nil {
+		e.errorHandler(*failure)
+	}
+	return failure
+}
+
+// matchListeners returns the listeners an emit of eventName reaches, in call order.
+// Callers must hold e.mu.
+func (e *EventEmitter) matchListeners(eventName string) []*Listener {
+	listeners := []*Listener{}
+	for _, pattern := range e.topics.match(eventName) {
+		listeners = append(listeners, e.listeners[pattern]...)
+	}
+
+	sortByPriority(listeners)
+	return listeners
+}
+
+// setListeners replaces the listeners registered for a name or pattern and
+// keeps the topic trie in sync. Callers must hold e.mu.
+func (e *EventEmitter) setListeners(pattern string, listeners []*Listener) {
+	_, exists := e.listeners[pattern]
+	if len(listeners) == 0 {
+		if exists {
+			delete(e.listeners, pattern)
+			e.topics.remove(pattern)
+		}
+		return
+	}
+
+	if !exists {
+		e.topics.insert(pattern)
+	}
+	e.listeners[pattern] = listeners
+}
+
+// sortByPriority puts listeners in call order: highest priority first,
+// then by registration.
+func sortByPriority(listeners []*Listener) {
+	slices.SortFunc(listeners, func(a, b *Listener) int {
+		if a.Priority != b.Priority {
+			return cmp.Compare(b.Priority, a.Priority)
+		}
+		return cmp.Compare(a.seq, b.seq)
+	})
 }
 
-// ListenerCount returns the number of listeners for an event.
+// ListenerCount returns the number of listeners an emit of eventName would
+// call, counting those registered for matching patterns.
 // If eventName is empty, returns total listeners for all events.
 func (e *EventEmitter) ListenerCount(eventName string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	total := 0
 
 	if eventName == "" {
@@ -170,15 +418,14 @@ func (e *EventEmitter) ListenerCount(eventName string) int {
 		return total
 	}
 
-	if listeners, exists := e.listeners[eventName]; exists {
-		return len(listeners)
-	}
-
-	return 0
+	return len(e.matchListeners(eventName))
 }
 
-// EventNames returns all event names that have at least one listener.
+// EventNames returns all event names and patterns that have at least one listener.
 func (e *EventEmitter) EventNames() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	result := []string{}
 	for eventName, listeners := range e.listeners {
 		if len(listeners) > 0 {
@@ -188,42 +435,33 @@ func (e *EventEmitter) EventNames() []string {
 	return result
 }
 
-// GetListeners returns all listeners for an event.
+// GetListeners returns all listeners an emit of eventName would call,
+// including those registered for matching patterns, in call order.
 // Returns empty slice if no listeners.
 func (e *EventEmitter) GetListeners(eventName string) []*Listener {
-	if listeners, exists := e.listeners[eventName]; exists {
-		return listeners
-	}
-	return []*Listener{}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.matchListeners(eventName)
 }
 
-// GetEventLogs returns all event logs, sorted by timestamp ascending.
+// GetEventLogs returns all retained event logs, sorted by timestamp ascending.
 func (e *EventEmitter) GetEventLogs() []EventLog {
-	result := make([]EventLog, len(e.eventLogs))
-	copy(result, e.eventLogs)
-	slices.SortFunc(result, func(a, b EventLog) int {
-		return a.Timestamp.Compare(b.Timestamp)
-	})
-
-	return result
+	return e.QueryEventLogs(LogQuery{}).Logs
 }
 
-// GetEventLogsByName returns all logs for a specific event name.
+// GetEventLogsByName returns all retained logs for a specific event name,
+// or for every event matching a pattern.
 // Sorted by timestamp ascending.
 func (e *EventEmitter) GetEventLogsByName(eventName string) []EventLog {
-	result := []EventLog{}
-	for _, eventLog := range e.eventLogs {
-		if eventLog.EventName == eventName {
-			result = append(result, eventLog)
-		}
-	}
-	return result
+	return e.QueryEventLogs(LogQuery{EventName: eventName}).Logs
 }
 
 // ClearEventLogs removes all event logs.
 // Returns the number of logs cleared.
 func (e *EventEmitter) ClearEventLogs() int {
-	removed := len(e.eventLogs)
-	e.eventLogs = make([]EventLog, 0)
-	return removed
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.eventLogs.clear()
 }
@@ -28,7 +28,7 @@ func TestOnAndEmit(t *testing.T) {
 	}
 
 	// Emit event
-	count := e.Emit("message", "hello")
+	count := e.Emit("message", "hello").Delivered
 	if count != 1 {
 		t.Errorf("expected 1 listener called, got %d", count)
 	}
@@ -43,7 +43,7 @@ func TestOnAndEmit(t *testing.T) {
 	}
 
 	// Emit non-existent event
-	count = e.Emit("other", "data")
+	count = e.Emit("other", "data").Delivered
 	if count != 0 {
 		t.Error("non-existent event should have 0 listeners")
 	}
@@ -57,7 +57,7 @@ func TestMultipleListeners(t *testing.T) {
 	e.On("event", func(data interface{}) { calls[1]++ })
 	e.On("event", func(data interface{}) { calls[2]++ })
 
-	count := e.Emit("event", nil)
+	count := e.Emit("event", nil).Delivered
 	if count != 3 {
 		t.Errorf("expected 3 listeners, got %d", count)
 	}
@@ -104,7 +104,7 @@ func TestOnceMixedWithOn(t *testing.T) {
 	e.On("event", func(data interface{}) { regularCalls++ })
 
 	// First emit
-	count := e.Emit("event", nil)
+	count := e.Emit("event", nil).Delivered
 	if count != 3 {
 		t.Errorf("first emit should call 3 listeners, got %d", count)
 	}
@@ -113,7 +113,7 @@ func TestOnceMixedWithOn(t *testing.T) {
 	}
 
 	// Second emit
-	count = e.Emit("event", nil)
+	count = e.Emit("event", nil).Delivered
 	if count != 2 {
 		t.Errorf("second emit should call 2 listeners, got %d", count)
 	}
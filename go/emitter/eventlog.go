@@ -0,0 +1,218 @@
+package emitter
+
+import (
+	"sort"
+	"time"
+)
+
+const defaultLogCapacity = 10000
+
+// LogQuery selects event logs for QueryEventLogs. Zero fields do not filter.
+type LogQuery struct {
+	EventName string    // event name or pattern the logged event must match
+	Since     time.Time // logged at or after Since
+	Until     time.Time // logged before Until
+	AfterSeq  uint64    // Seq greater than AfterSeq; LogPage.NextSeq continues a query
+	Limit     int       // at most Limit logs per page
+}
+
+// LogPage is one page of QueryEventLogs results, oldest first.
+type LogPage struct {
+	Logs    []EventLog
+	NextSeq uint64 // AfterSeq for the next page, 0 if there are no more matches
+}
+
+// QueryEventLogs returns the retained logs matching q, oldest first.
+// A page ends after q.Limit logs; pass its NextSeq as AfterSeq to get the
+// next one. Paging by Seq stays consistent while new events are logged.
+func (e *EventEmitter) QueryEventLogs(q LogQuery) LogPage {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.eventLogs.query(q, time.Now())
+}
+
+// Replay calls listener with every retained event logged at or after since,
+// then keeps calling it with each event logged from then on, so a late
+// subscriber sees a complete, ordered stream without gaps or duplicates.
+// Past events are replayed on the caller's goroutine before Replay returns;
+// live ones are delivered by the goroutine that logged them, after any
+// synchronous listeners ran. A panicking listener is recovered and
+// reported to the error handler as a ListenerError, and later events still
+// reach it. The listener is not counted by ListenerCount and is removed
+// with Off.
+// Returns the listener ID.
+func (e *EventEmitter) Replay(since time.Time, listener func(EventLog)) string {
+	e.mu.Lock()
+	e.listenerSeq++
+	tap := &logTap{Listener: &Listener{
+		ID:        formatListenerID(e.listenerSeq),
+		CreatedAt: time.Now(),
+		receive:   func(_ string, data interface{}) { listener(data.(EventLog)) },
+	}}
+	pending := e.eventLogs.query(LogQuery{Since: since}, time.Now()).Logs
+	e.taps = append(e.taps, tap)
+	e.mu.Unlock()
+
+	// Events logged while replaying queue up in tap.pending until it goes live
+	for {
+		for _, entry := range pending {
+			e.call(tap.Listener, entry.EventName, entry)
+		}
+
+		e.mu.Lock()
+		pending, tap.pending = tap.pending, nil
+		if len(pending) == 0 {
+			tap.live = true
+			e.mu.Unlock()
+			return tap.ID
+		}
+		e.mu.Unlock()
+	}
+}
+
+// logTap is a Replay subscriber to the event log.
+type logTap struct {
+	*Listener            // called like a Subscribe listener, with the EventLog as data
+	live      bool       // replay done, deliver directly; guarded by EventEmitter.mu
+	pending   []EventLog // logged during the replay; guarded by EventEmitter.mu
+}
+
+// logEvent records an event in the log and passes it on to Replay subscribers.
+func (e *EventEmitter) logEvent(entry EventLog) {
+	e.mu.Lock()
+	entry = e.eventLogs.append(entry, time.Now())
+	live := []*logTap{}
+	for _, tap := range e.taps {
+		if tap.live {
+			live = append(live, tap)
+		} else {
+			tap.pending = append(tap.pending, entry)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, tap := range live {
+		e.call(tap.Listener, entry.EventName, entry)
+	}
+}
+
+// removeTap unregisters a Replay subscriber. Callers must hold e.mu.
+func (e *EventEmitter) removeTap(id string) bool {
+	for i, tap := range e.taps {
+		if tap.ID == id {
+			e.taps = append(e.taps[:i], e.taps[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// eventLog is a ring buffer of the most recent logs, oldest first. Entries
+// are appended in Timestamp and Seq order, so both can be binary searched.
+type eventLog struct {
+	entries   []EventLog // grows up to capacity, then wraps around
+	start     int        // index of the oldest entry
+	count     int
+	capacity  int
+	retention time.Duration // 0 keeps entries until they are overwritten
+	nextSeq   uint64
+}
+
+func newEventLog(capacity int, retention time.Duration) *eventLog {
+	return &eventLog{capacity: capacity, retention: retention, nextSeq: 1}
+}
+
+// append stamps entry with its Seq and Timestamp and stores it, overwriting
+// the oldest entry when the log is full.
+func (l *eventLog) append(entry EventLog, now time.Time) EventLog {
+	entry.Seq = l.nextSeq
+	entry.Timestamp = now
+	l.nextSeq++
+	l.expire(now)
+
+	switch {
+	case l.count < len(l.entries):
+		l.entries[(l.start+l.count)%len(l.entries)] = entry
+		l.count++
+	case len(l.entries) < l.capacity:
+		// Only reorder if expiry moved the oldest entry off the front
+		if l.start != 0 {
+			l.entries = l.ordered()
+			l.start = 0
+		}
+		l.entries = append(l.entries, entry)
+		l.count++
+	default:
+		l.entries[l.start] = entry
+		l.start = (l.start + 1) % len(l.entries)
+	}
+	return entry
+}
+
+// at returns the i-th oldest entry.
+func (l *eventLog) at(i int) *EventLog {
+	return &l.entries[(l.start+i)%len(l.entries)]
+}
+
+// ordered copies the entries, oldest first.
+func (l *eventLog) ordered() []EventLog {
+	result := make([]EventLog, l.count)
+	for i := range result {
+		result[i] = *l.at(i)
+	}
+	return result
+}
+
+// expire drops the entries older than the retention age.
+func (l *eventLog) expire(now time.Time) {
+	if l.retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-l.retention)
+	for l.count > 0 && l.at(0).Timestamp.Before(cutoff) {
+		*l.at(0) = EventLog{}
+		l.start = (l.start + 1) % len(l.entries)
+		l.count--
+	}
+}
+
+func (l *eventLog) clear() int {
+	removed := l.count
+	l.entries = nil
+	l.start = 0
+	l.count = 0
+	return removed
+}
+
+func (l *eventLog) query(q LogQuery, now time.Time) LogPage {
+	l.expire(now)
+
+	first := sort.Search(l.count, func(i int) bool {
+		entry := l.at(i)
+		return entry.Seq > q.AfterSeq && !entry.Timestamp.Before(q.Since)
+	})
+
+	var names *topicTrie
+	if q.EventName != "" {
+		names = newTopicTrie()
+		names.insert(q.EventName)
+	}
+
+	page := LogPage{Logs: []EventLog{}}
+	for i := first; i < l.count; i++ {
+		entry := l.at(i)
+		if !q.Until.IsZero() && !entry.Timestamp.Before(q.Until) {
+			break
+		}
+		if names != nil && len(names.match(entry.EventName)) == 0 {
+			continue
+		}
+		if q.Limit > 0 && len(page.Logs) == q.Limit {
+			page.NextSeq = page.Logs[len(page.Logs)-1].Seq
+			break
+		}
+		page.Logs = append(page.Logs, *entry)
+	}
+	return page
+}
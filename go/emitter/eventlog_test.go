@@ -0,0 +1,236 @@
+package emitter
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func logSeqs(logs []EventLog) []uint64 {
+	seqs := make([]uint64, len(logs))
+	for i, entry := range logs {
+		seqs[i] = entry.Seq
+	}
+	return seqs
+}
+
+func TestEventLogCapacity(t *testing.T) {
+	e := NewEventEmitter(WithLogCapacity(3))
+
+	for i := 1; i <= 5; i++ {
+		e.Emit("event", i)
+	}
+
+	logs := e.GetEventLogs()
+	if len(logs) != 3 {
+		t.Fatalf("log should keep 3 events, got %d", len(logs))
+	}
+	if logs[0].Data != 3 || logs[2].Data != 5 {
+		t.Errorf("log should keep the newest events, got %v .. %v", logs[0].Data, logs[2].Data)
+	}
+	if !slices.Equal(logSeqs(logs), []uint64{3, 4, 5}) {
+		t.Errorf("sequence numbers should survive overwrites, got %v", logSeqs(logs))
+	}
+
+	if cleared := e.ClearEventLogs(); cleared != 3 {
+		t.Errorf("expected 3 cleared, got %d", cleared)
+	}
+	e.Emit("event", 6)
+	if logs := e.GetEventLogs(); len(logs) != 1 || logs[0].Seq != 6 {
+		t.Errorf("log should keep counting after Clear, got %+v", logs)
+	}
+}
+
+func TestEventLogRetention(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := newEventLog(100, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		log.append(EventLog{EventName: "event", Data: i}, start.Add(time.Duration(i)*30*time.Second))
+	}
+
+	// At 2m, entries logged before 1m are too old
+	page := log.query(LogQuery{}, start.Add(2*time.Minute))
+	if !slices.Equal(logSeqs(page.Logs), []uint64{3, 4, 5}) {
+		t.Errorf("expected entries 3-5 to be retained, got %v", logSeqs(page.Logs))
+	}
+
+	// Expiring and wrapping together keep the ring consistent
+	for i := 5; i < 10; i++ {
+		log.append(EventLog{EventName: "event", Data: i}, start.Add(time.Duration(i)*30*time.Second))
+	}
+	page = log.query(LogQuery{}, start.Add(5*time.Minute))
+	if !slices.Equal(logSeqs(page.Logs), []uint64{9, 10}) {
+		t.Errorf("expected entries 9-10 to be retained, got %v", logSeqs(page.Logs))
+	}
+}
+
+func TestEventLogWrapsAfterExpiry(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := newEventLog(4, time.Minute)
+
+	for i := 0; i < 20; i++ {
+		log.append(EventLog{EventName: "event"}, start.Add(time.Duration(i)*20*time.Second))
+		page := log.query(LogQuery{}, start.Add(time.Duration(i)*20*time.Second))
+		if len(page.Logs) > 4 {
+			t.Fatalf("log should never exceed its capacity, has %d", len(page.Logs))
+		}
+		seqs := logSeqs(page.Logs)
+		if seqs[len(seqs)-1] != uint64(i+1) || !slices.IsSorted(seqs) {
+			t.Fatalf("log should end with the newest entry in order, got %v", seqs)
+		}
+	}
+}
+
+func TestQueryEventLogs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := newEventLog(100, 0)
+	names := []string{"order.created", "user.created", "order.shipped", "order.item.added"}
+	for i := 0; i < 12; i++ {
+		log.append(EventLog{EventName: names[i%len(names)], Data: i}, start.Add(time.Duration(i)*time.Second))
+	}
+	now := start.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		query LogQuery
+		want  []uint64
+	}{
+		{"all", LogQuery{}, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+		{"by name", LogQuery{EventName: "user.created"}, []uint64{2, 6, 10}},
+		{"by pattern", LogQuery{EventName: "order.*"}, []uint64{1, 3, 5, 7, 9, 11}},
+		{"since", LogQuery{Since: start.Add(9 * time.Second)}, []uint64{10, 11, 12}},
+		{"until", LogQuery{Until: start.Add(2 * time.Second)}, []uint64{1, 2}},
+		{"range and name", LogQuery{EventName: "order.**", Since: start.Add(2 * time.Second), Until: start.Add(8 * time.Second)}, []uint64{3, 4, 5, 7, 8}},
+		{"after seq", LogQuery{AfterSeq: 10}, []uint64{11, 12}},
+	}
+
+	for _, tt := range tests {
+		page := log.query(tt.query, now)
+		if !slices.Equal(logSeqs(page.Logs), tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, logSeqs(page.Logs))
+		}
+		if page.NextSeq != 0 {
+			t.Errorf("%s: unlimited query should have no next page", tt.name)
+		}
+	}
+}
+
+func TestQueryEventLogsPagination(t *testing.T) {
+	e := NewEventEmitter()
+	for i := 0; i < 10; i++ {
+		e.Emit("event."+strconv.Itoa(i%2), i)
+	}
+
+	query := LogQuery{EventName: "event.0", Limit: 2}
+	pages := [][]uint64{}
+	for {
+		page := e.QueryEventLogs(query)
+		pages = append(pages, logSeqs(page.Logs))
+		if page.NextSeq == 0 {
+			break
+		}
+		query.AfterSeq = page.NextSeq
+	}
+
+	want := [][]uint64{{1, 3}, {5, 7}, {9}}
+	if len(pages) != len(want) {
+		t.Fatalf("expected %d pages, got %v", len(want), pages)
+	}
+	for i := range want {
+		if !slices.Equal(pages[i], want[i]) {
+			t.Errorf("page %d: expected %v, got %v", i, want[i], pages[i])
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	e := NewEventEmitter()
+
+	e.Emit("old", 1)
+	time.Sleep(time.Millisecond)
+	since := time.Now()
+	e.Emit("event", 2)
+	e.EmitAsync("event", 3)
+
+	received := []interface{}{}
+	id := e.Replay(since, func(entry EventLog) {
+		received = append(received, entry.Data)
+		// Events logged during the replay come after it
+		if entry.Data == 2 {
+			e.Emit("during", 4)
+		}
+	})
+
+	if !slices.Equal(received, []interface{}{2, 3, 4}) {
+		t.Errorf("expected past events then events logged during replay, got %v", received)
+	}
+	if e.ListenerCount("") != 0 {
+		t.Error("replay listeners should not be counted as event listeners")
+	}
+
+	e.Emit("live", 5)
+	if !slices.Equal(received, []interface{}{2, 3, 4, 5}) {
+		t.Errorf("live events should follow the replay, got %v", received)
+	}
+
+	if !e.Off(id) {
+		t.Error("Off should remove the replay listener")
+	}
+	e.Emit("live", 6)
+	if len(received) != 4 {
+		t.Error("removed replay listener should not receive events")
+	}
+}
+
+func TestReplayWithoutHistory(t *testing.T) {
+	e := NewEventEmitter()
+
+	received := []EventLog{}
+	e.Replay(time.Now(), func(entry EventLog) { received = append(received, entry) })
+
+	e.On("event", func(data interface{}) {})
+	e.Emit("event", "data")
+
+	if len(received) != 1 || received[0].EventName != "event" || received[0].Listeners != 1 || received[0].Seq != 1 {
+		t.Errorf("live events should arrive as complete logs, got %+v", received)
+	}
+}
+
+func TestReplayRecoversPanics(t *testing.T) {
+	var failures []ListenerError
+	e := NewEventEmitter(WithErrorHandler(func(le ListenerError) {
+		failures = append(failures, le)
+	}))
+	e.Emit("past", 1)
+
+	received := []interface{}{}
+	id := e.Replay(time.Time{}, func(entry EventLog) {
+		received = append(received, entry.Data)
+		panic("replay listener")
+	})
+	e.Emit("live", 2)
+
+	if !slices.Equal(received, []interface{}{1, 2}) {
+		t.Errorf("expected the listener to keep receiving events, got %v", received)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("expected both panics reported, got %v", failures)
+	}
+	var panicErr *PanicError
+	if failures[1].ListenerID != id || failures[1].EventName != "live" || !errors.As(failures[1].Err, &panicErr) {
+		t.Errorf("expected a PanicError from the replay listener, got %+v", failures[1])
+	}
+}
+
+func BenchmarkEventLogFill(b *testing.B) {
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		l := newEventLog(defaultLogCapacity, 0)
+		for j := 0; j < defaultLogCapacity; j++ {
+			l.append(EventLog{EventName: "event"}, now)
+		}
+	}
+}
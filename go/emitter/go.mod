@@ -0,0 +1,3 @@
+module emitter
+
+go 1.23
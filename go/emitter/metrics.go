@@ -0,0 +1,209 @@
+package emitter
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultLatencyBuckets are the upper bounds of the listener latency
+// histogram, from 100µs to 10s.
+var defaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Metrics is a snapshot of what the emitter counted since it was created.
+type Metrics struct {
+	Events    map[string]EventMetrics // by event name
+	Listeners []ListenerMetrics       // registered listeners, in registration order
+}
+
+// EventMetrics counts one event name.
+type EventMetrics struct {
+	Emitted uint64    // Emit and EmitAsync calls
+	Errors  uint64    // listener failures while handling it
+	Latency Histogram // time Emit spent calling the listeners, or EmitAsync queueing for them
+}
+
+// ListenerMetrics describes one registered listener.
+type ListenerMetrics struct {
+	ListenerID string
+	EventName  string // the name or pattern it registered for
+	Calls      uint64
+	Errors     uint64
+	SlowCalls  uint64 // calls that took longer than WithSlowListenerThreshold
+	Latency    Histogram
+	QueueDepth int // events waiting in its EmitAsync queue
+}
+
+// Histogram counts durations into buckets.
+type Histogram struct {
+	Bounds []time.Duration // bucket upper bounds, ascending
+	Counts []uint64        // per bucket, not cumulative; the last one is above every bound
+	Count  uint64
+	Sum    time.Duration
+}
+
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(h.Bounds, d)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = slices.Clone(h.Counts)
+	return h
+}
+
+// metrics holds the counters behind Metrics. Its lock is taken after e.mu,
+// never before.
+type metrics struct {
+	mu            sync.Mutex
+	buckets       []time.Duration
+	slowThreshold time.Duration
+	logger        *slog.Logger
+	events        map[string]*EventMetrics
+}
+
+// listenerStats are the counters of one listener, guarded by metrics.mu.
+type listenerStats struct {
+	calls   uint64
+	errors  uint64
+	slow    uint64
+	latency Histogram
+}
+
+func newMetrics(o options) *metrics {
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &metrics{
+		buckets:       o.latencyBuckets,
+		slowThreshold: o.slowThreshold,
+		logger:        logger,
+		events:        make(map[string]*EventMetrics),
+	}
+}
+
+// event returns the counters for eventName. Callers must hold m.mu.
+func (m *metrics) event(eventName string) *EventMetrics {
+	em, exists := m.events[eventName]
+	if !exists {
+		em = &EventMetrics{Latency: newHistogram(m.buckets)}
+		m.events[eventName] = em
+	}
+	return em
+}
+
+func (m *metrics) emitted(eventName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.event(eventName).Emitted++
+}
+
+// dispatched records how long an emit took to dispatch the event.
+func (m *metrics) dispatched(eventName string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.event(eventName).Latency.observe(elapsed)
+}
+
+func (m *metrics) failed(l *Listener, eventName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.event(eventName).Errors++
+	l.stats.errors++
+}
+
+// called records a listener call and warns if it was slow.
+func (m *metrics) called(l *Listener, eventName string, elapsed time.Duration) {
+	m.mu.Lock()
+	if l.stats.latency.Counts == nil {
+		l.stats.latency = newHistogram(m.buckets)
+	}
+	l.stats.calls++
+	l.stats.latency.observe(elapsed)
+	slow := m.slowThreshold > 0 && elapsed > m.slowThreshold
+	if slow {
+		l.stats.slow++
+	}
+	m.mu.Unlock()
+
+	if slow {
+		m.logger.Warn("emitter: slow listener",
+			"listener", l.ID,
+			"event", eventName,
+			"duration", elapsed,
+			"threshold", m.slowThreshold)
+	}
+}
+
+// Metrics returns per-event and per-listener counters. Listeners that were
+// removed are left out; their calls still count for the events.
+func (e *EventEmitter) Metrics() Metrics {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	listeners := []*Listener{}
+	for _, registered := range e.listeners {
+		listeners = append(listeners, registered...)
+	}
+	slices.SortFunc(listeners, func(a, b *Listener) int {
+		return a.seq - b.seq
+	})
+
+	depths := make([]int, len(listeners))
+	for i, l := range listeners {
+		if l.queue != nil {
+			depths[i] = l.queue.len()
+		}
+	}
+
+	m := e.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := Metrics{
+		Events:    make(map[string]EventMetrics, len(m.events)),
+		Listeners: make([]ListenerMetrics, len(listeners)),
+	}
+	for name, em := range m.events {
+		snapshot.Events[name] = EventMetrics{Emitted: em.Emitted, Errors: em.Errors, Latency: em.Latency.clone()}
+	}
+	for i, l := range listeners {
+		latency := l.stats.latency.clone()
+		if latency.Counts == nil {
+			latency = newHistogram(m.buckets)
+		}
+		snapshot.Listeners[i] = ListenerMetrics{
+			ListenerID: l.ID,
+			EventName:  l.EventName,
+			Calls:      l.stats.calls,
+			Errors:     l.stats.errors,
+			SlowCalls:  l.stats.slow,
+			Latency:    latency,
+			QueueDepth: depths[i],
+		}
+	}
+	return snapshot
+}
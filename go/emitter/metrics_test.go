@@ -0,0 +1,154 @@
+package emitter
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsCountsEmitsAndErrors(t *testing.T) {
+	e := NewEventEmitter()
+	e.On("order.*", func(data interface{}) {})
+	e.OnErr("order.created", func(data interface{}) error { return errors.New("failed") })
+
+	e.Emit("order.created", nil)
+	e.Emit("order.created", nil)
+	e.Emit("order.paid", nil)
+	e.Emit("nobody.listens", nil)
+
+	m := e.Metrics()
+	want := map[string]EventMetrics{
+		"order.created":  {Emitted: 2, Errors: 2},
+		"order.paid":     {Emitted: 1},
+		"nobody.listens": {Emitted: 1},
+	}
+	counts := map[string]EventMetrics{}
+	for name, em := range m.Events {
+		counts[name] = EventMetrics{Emitted: em.Emitted, Errors: em.Errors}
+		if em.Latency.Count != em.Emitted || len(em.Latency.Counts) != len(defaultLatencyBuckets)+1 {
+			t.Errorf("expected a dispatch latency per emit of %s, got %+v", name, em.Latency)
+		}
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected event metrics %+v, got %+v", want, counts)
+	}
+
+	if len(m.Listeners) != 2 {
+		t.Fatalf("expected 2 listeners, got %d", len(m.Listeners))
+	}
+	wildcard, failing := m.Listeners[0], m.Listeners[1]
+	if wildcard.ListenerID != "L-1" || wildcard.EventName != "order.*" || wildcard.Calls != 3 || wildcard.Errors != 0 {
+		t.Errorf("unexpected wildcard listener metrics %+v", wildcard)
+	}
+	if failing.Calls != 2 || failing.Errors != 2 {
+		t.Errorf("expected 2 calls and 2 errors, got %+v", failing)
+	}
+	if wildcard.Latency.Count != 3 || len(wildcard.Latency.Counts) != len(defaultLatencyBuckets)+1 {
+		t.Errorf("expected 3 latency observations, got %+v", wildcard.Latency)
+	}
+}
+
+func TestMetricsEventLatency(t *testing.T) {
+	e := NewEventEmitter()
+	e.On("sync", func(data interface{}) { time.Sleep(5 * time.Millisecond) })
+	g := newGatedListener()
+	e.On("async", g.callback)
+
+	e.Emit("sync", nil)
+	e.EmitAsync("async", nil)
+
+	<-g.started
+	m := e.Metrics()
+	if latency := m.Events["sync"].Latency; latency.Count != 1 || latency.Sum < 5*time.Millisecond {
+		t.Errorf("expected Emit's latency to include its listeners, got %+v", latency)
+	}
+	// EmitAsync only queues, so it does not wait for the blocked listener
+	if latency := m.Events["async"].Latency; latency.Count != 1 {
+		t.Errorf("expected one EmitAsync latency, got %+v", latency)
+	}
+	close(g.gate)
+	e.Close()
+}
+
+func TestMetricsOmitRemovedListeners(t *testing.T) {
+	e := NewEventEmitter()
+	id := e.On("event", func(data interface{}) {})
+	e.Emit("event", nil)
+	e.Off(id)
+
+	m := e.Metrics()
+	if len(m.Listeners) != 0 {
+		t.Errorf("expected no listeners, got %+v", m.Listeners)
+	}
+	if m.Events["event"].Emitted != 1 {
+		t.Errorf("expected the emit to stay counted, got %+v", m.Events)
+	}
+}
+
+func TestMetricsQueueDepth(t *testing.T) {
+	e := NewEventEmitter()
+	g := newGatedListener()
+	e.On("event", g.callback)
+
+	for i := 0; i < 4; i++ {
+		e.EmitAsync("event", i)
+	}
+	<-g.started
+	if depth := e.Metrics().Listeners[0].QueueDepth; depth != 3 {
+		t.Errorf("expected 3 queued events behind the blocked one, got %d", depth)
+	}
+
+	close(g.gate)
+	e.Close()
+	if depth := e.Metrics().Listeners[0].QueueDepth; depth != 0 {
+		t.Errorf("expected an empty queue after Close, got %d", depth)
+	}
+}
+
+func TestSlowListenerWarning(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEventEmitter(
+		WithSlowListenerThreshold(time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+	)
+	e.On("event", func(data interface{}) {})
+	slowID := e.On("event", func(data interface{}) { time.Sleep(5 * time.Millisecond) })
+
+	e.Emit("event", nil)
+
+	out := buf.String()
+	if strings.Count(out, "slow listener") != 1 || !strings.Contains(out, "listener="+slowID) {
+		t.Errorf("expected one warning naming %s, got %q", slowID, out)
+	}
+	m := e.Metrics()
+	if m.Listeners[0].SlowCalls != 0 || m.Listeners[1].SlowCalls != 1 {
+		t.Errorf("expected only the slow listener counted, got %+v", m.Listeners)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	e := NewEventEmitter(WithLatencyBuckets(10*time.Millisecond, time.Millisecond))
+	e.On("event", func(data interface{}) {})
+	e.Emit("event", nil)
+
+	h := e.Metrics().Listeners[0].Latency
+	if !reflect.DeepEqual(h.Bounds, []time.Duration{time.Millisecond, 10 * time.Millisecond}) {
+		t.Errorf("expected sorted bounds, got %v", h.Bounds)
+	}
+
+	h = newHistogram(h.Bounds)
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, 5 * time.Millisecond, time.Second} {
+		h.observe(d)
+	}
+	// A bound is inclusive, as with Prometheus' le
+	if !reflect.DeepEqual(h.Counts, []uint64{2, 1, 1}) || h.Count != 4 {
+		t.Errorf("unexpected bucket counts %v", h.Counts)
+	}
+	if h.Sum != time.Second+6*time.Millisecond+time.Microsecond {
+		t.Errorf("unexpected sum %v", h.Sum)
+	}
+}
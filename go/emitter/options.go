@@ -0,0 +1,125 @@
+package emitter
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const defaultQueueSize = 64
+
+type options struct {
+	queueSize    int
+	overflow     OverflowPolicy
+	errorHandler func(ListenerError)
+	logCapacity  int
+	logRetention time.Duration
+	outbox       *Outbox
+
+	latencyBuckets []time.Duration
+	slowThreshold  time.Duration
+	logger         *slog.Logger
+}
+
+// Option configures an EventEmitter at construction time.
+type Option func(*options)
+
+// WithQueueSize sets how many events EmitAsync may queue per listener
+// before the overflow policy applies. The default is 64.
+func WithQueueSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.queueSize = size
+		}
+	}
+}
+
+// WithOverflow selects what EmitAsync does when a listener's queue is full.
+// The default is OverflowBlock.
+func WithOverflow(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// WithErrorHandler sets a function called with every listener failure,
+// synchronous or not. It is the only place failures of EmitAsync deliveries
+// are reported. The handler runs on the goroutine that called the listener.
+func WithErrorHandler(handler func(ListenerError)) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// WithLogCapacity sets how many events the event log keeps; once full,
+// each new event overwrites the oldest. The default is 10000.
+func WithLogCapacity(capacity int) Option {
+	return func(o *options) {
+		if capacity > 0 {
+			o.logCapacity = capacity
+		}
+	}
+}
+
+// WithLogRetention drops logged events once they are older than age.
+// By default events stay until the log overwrites them.
+func WithLogRetention(age time.Duration) Option {
+	return func(o *options) {
+		o.logRetention = age
+	}
+}
+
+// WithLatencyBuckets sets the upper bounds of the listener latency
+// histograms in Metrics. The default runs from 100µs to 10s.
+func WithLatencyBuckets(bounds ...time.Duration) Option {
+	return func(o *options) {
+		if len(bounds) > 0 {
+			o.latencyBuckets = slices.Sorted(slices.Values(bounds))
+		}
+	}
+}
+
+// WithSlowListenerThreshold logs a warning with the listener ID whenever a
+// listener call takes longer than threshold, and counts it in SlowCalls.
+// Off by default.
+func WithSlowListenerThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = threshold
+	}
+}
+
+// WithLogger sets where warnings such as slow listeners are logged.
+// The default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// ListenerOption configures a listener at registration time.
+type ListenerOption func(*Listener)
+
+// WithPriority orders the listener among those an event reaches: higher
+// priorities are called first, equal ones in registration order.
+// The default priority is 0.
+func WithPriority(priority int) ListenerOption {
+	return func(l *Listener) {
+		l.Priority = priority
+	}
+}
+
+// WithFilter skips events whose data the predicate rejects. Skipped events
+// do not count against WithMaxCalls.
+func WithFilter(filter func(data interface{}) bool) ListenerOption {
+	return func(l *Listener) {
+		l.Filter = filter
+	}
+}
+
+// WithMaxCalls removes the listener after it has been called n times;
+// Once is WithMaxCalls(1). The default, 0, means unlimited.
+func WithMaxCalls(n int) ListenerOption {
+	return func(l *Listener) {
+		l.MaxCalls = n
+	}
+}
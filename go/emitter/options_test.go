@@ -0,0 +1,171 @@
+package emitter
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPriorityOrdersListeners(t *testing.T) {
+	e := NewEventEmitter()
+
+	order := []string{}
+	e.On("event", func(data interface{}) { order = append(order, "default") })
+	e.On("event", func(data interface{}) { order = append(order, "low") }, WithPriority(-5))
+	e.On("event", func(data interface{}) { order = append(order, "high") }, WithPriority(10))
+	e.On("event", func(data interface{}) { order = append(order, "default2") })
+	e.On("*", func(data interface{}) { order = append(order, "pattern") }, WithPriority(5))
+	e.On("event", func(data interface{}) { order = append(order, "high2") }, WithPriority(10))
+
+	e.Emit("event", nil)
+	want := []string{"high", "high2", "pattern", "default", "default2", "low"}
+	if !slices.Equal(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+
+	priorities := []int{}
+	for _, l := range e.GetListeners("event") {
+		priorities = append(priorities, l.Priority)
+	}
+	if !slices.Equal(priorities, []int{10, 10, 5, 0, 0, -5}) {
+		t.Errorf("GetListeners should return call order, got priorities %v", priorities)
+	}
+}
+
+func TestExtremePriorities(t *testing.T) {
+	e := NewEventEmitter()
+
+	order := []string{}
+	e.On("event", func(data interface{}) { order = append(order, "min") }, WithPriority(math.MinInt))
+	e.On("event", func(data interface{}) { order = append(order, "negative") }, WithPriority(-1))
+	e.On("event", func(data interface{}) { order = append(order, "max") }, WithPriority(math.MaxInt))
+
+	e.Emit("event", nil)
+	if want := []string{"max", "negative", "min"}; !slices.Equal(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
+
+func TestFilterSkipsEvents(t *testing.T) {
+	e := NewEventEmitter()
+
+	received := []interface{}{}
+	e.On("order.*", func(data interface{}) { received = append(received, data) },
+		WithFilter(func(data interface{}) bool { return data.(int) > 100 }))
+
+	e.Emit("order.created", 50)
+	result := e.Emit("order.created", 150)
+
+	if !slices.Equal(received, []interface{}{150}) {
+		t.Errorf("only accepted events should reach the callback, got %v", received)
+	}
+	if result.Delivered != 1 {
+		t.Errorf("expected 1 delivery, got %d", result.Delivered)
+	}
+	if logs := e.GetEventLogs(); logs[0].Listeners != 0 || logs[1].Listeners != 1 {
+		t.Error("filtered events should not count as delivered in the log")
+	}
+}
+
+func TestFilterPanicIsReported(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := 0
+	e.On("event", func(data interface{}) { calls++ },
+		WithFilter(func(data interface{}) bool { return data.(string) != "" }))
+
+	result := e.Emit("event", 42)
+	var panicErr *PanicError
+	if calls != 0 || result.Delivered != 0 || !errors.As(result.Err(), &panicErr) {
+		t.Errorf("a panicking filter should skip the listener and be reported, got %+v", result)
+	}
+}
+
+func TestMaxCalls(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := 0
+	id := e.On("event", func(data interface{}) { calls++ },
+		WithMaxCalls(3), WithFilter(func(data interface{}) bool { return data != "skip" }))
+
+	if l := e.GetListeners("event")[0]; l.MaxCalls != 3 || l.Once {
+		t.Errorf("listener should have MaxCalls 3 and not be Once, got %+v", l)
+	}
+
+	for _, data := range []string{"a", "skip", "b", "skip", "c", "d"} {
+		e.Emit("event", data)
+	}
+	if calls != 3 {
+		t.Errorf("listener should be called 3 times, got %d", calls)
+	}
+	if e.Off(id) {
+		t.Error("listener should be removed after reaching MaxCalls")
+	}
+}
+
+func TestMaxCallsConcurrent(t *testing.T) {
+	e := NewEventEmitter()
+
+	var mu sync.Mutex
+	calls := 0
+	e.On("event", func(data interface{}) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	}, WithMaxCalls(10))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if j%2 == 0 {
+					e.Emit("event", nil)
+				} else {
+					e.EmitAsync("event", nil)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	e.Close()
+
+	if calls != 10 {
+		t.Errorf("concurrent emits should call the listener exactly 10 times, got %d", calls)
+	}
+}
+
+func TestStopPropagation(t *testing.T) {
+	e := NewEventEmitter()
+
+	order := []string{}
+	e.On("event", func(data interface{}) { order = append(order, "low") }, WithPriority(-1))
+	e.Once("event", func(data interface{}) { order = append(order, "once") })
+	e.OnErr("event", func(data interface{}) error {
+		order = append(order, "guard")
+		if data == "stop" {
+			return ErrStopPropagation
+		}
+		return nil
+	}, WithPriority(1))
+
+	result := e.Emit("event", "stop")
+	if !slices.Equal(order, []string{"guard"}) {
+		t.Errorf("listeners after the guard should not run, got %v", order)
+	}
+	if result.Delivered != 1 || result.Err() != nil {
+		t.Errorf("stopping is not a failure, got %+v", result)
+	}
+	if e.ListenerCount("event") != 3 {
+		t.Error("the once listener should stay registered since it was never called")
+	}
+
+	order = order[:0]
+	e.Emit("event", "go")
+	if !slices.Equal(order, []string{"guard", "once", "low"}) {
+		t.Errorf("all listeners should run without a stop, got %v", order)
+	}
+}
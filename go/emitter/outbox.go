@@ -0,0 +1,802 @@
+package emitter
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentSize = 4 << 20
+	defaultAckInterval = 100 * time.Millisecond
+	segmentExt         = ".seg"
+	consumersFile      = "consumers.json"
+	frameHeaderSize    = 8 // payload length and CRC-32, both uint32
+)
+
+// ErrOutboxClosed is returned when writing to an outbox after Close.
+var ErrOutboxClosed = errors.New("emitter: outbox closed")
+
+// errTornFrame marks a frame that was only partly written or is corrupt.
+var errTornFrame = errors.New("emitter: torn outbox frame")
+
+// Codec converts event data to and from bytes for the outbox.
+type Codec interface {
+	Encode(data interface{}) ([]byte, error)
+	Decode(b []byte) (interface{}, error)
+}
+
+// GobCodec encodes data with encoding/gob, so redelivered data has the type
+// it was emitted with. Concrete types other than gob's built-in basic types
+// must be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Encode(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(b []byte) (interface{}, error) {
+	var data interface{}
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data)
+	return data, err
+}
+
+// JSONCodec encodes data with encoding/json. Redelivered data has JSON's
+// generic types, e.g. float64 for numbers and map[string]interface{} for structs.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (JSONCodec) Decode(b []byte) (interface{}, error) {
+	var data interface{}
+	err := json.Unmarshal(b, &data)
+	return data, err
+}
+
+type outboxOptions struct {
+	segmentSize int64
+	codec       Codec
+	ackInterval time.Duration
+}
+
+// OutboxOption configures an Outbox when it is opened.
+type OutboxOption func(*outboxOptions)
+
+// WithSegmentSize sets the size in bytes after which the outbox starts a new
+// segment file. Only whole segments are compacted. The default is 4 MiB.
+func WithSegmentSize(size int64) OutboxOption {
+	return func(o *outboxOptions) {
+		if size > 0 {
+			o.segmentSize = size
+		}
+	}
+}
+
+// WithAckInterval sets how often acknowledgements are written to disk; they
+// are batched in between, so a crash loses at most that much progress and
+// the events are redelivered. 0 writes every acknowledgement at once. The
+// default is 100ms.
+func WithAckInterval(d time.Duration) OutboxOption {
+	return func(o *outboxOptions) {
+		if d >= 0 {
+			o.ackInterval = d
+		}
+	}
+}
+
+// WithCodec selects how event data is stored. The default is GobCodec.
+func WithCodec(codec Codec) OutboxOption {
+	return func(o *outboxOptions) {
+		o.codec = codec
+	}
+}
+
+// Outbox is an append-only log of emitted events on disk, used with
+// WithOutbox and OnDurable for at-least-once delivery across restarts.
+//
+// Events are numbered by offset and stored in segment files named after
+// their first offset. Every frame carries its length and a CRC-32, so a
+// frame torn by a crash is detected and cut off when the outbox is reopened.
+// Each durable listener's acknowledged offset is kept in consumers.json:
+// every event up to it was handled. An event a listener failed, or that was
+// dropped from its EmitAsync queue, is moved to its dead letters, also kept
+// in consumers.json, so the offset moves on; OnDurable redelivers them when
+// the listener registers again. Segments below the lowest acknowledged
+// offset written to disk are removed by Compact, which also runs whenever a
+// new segment is started. If consumers.json cannot be read, as after a crash
+// on a filesystem that lost it, no event counts as acknowledged: every
+// durable listener gets every event still in the outbox redelivered, and
+// segments are only compacted once Compact is called explicitly, as the
+// listeners that have not registered again are unknown.
+type Outbox struct {
+	mu          sync.Mutex
+	dir         string
+	codec       Codec
+	segmentSize int64
+	segments    []*segment // oldest first; the last one is appended to
+	active      *os.File
+	nextOffset  uint64
+	consumers   map[string]*consumer
+	lostAcks    bool // consumers.json was unreadable: names start from the oldest event, no automatic compaction
+	closed      bool
+
+	ackInterval time.Duration
+	dirty       bool        // consumer state changed since it was written
+	flushTimer  *time.Timer // set while a write is scheduled
+	flushErr    error       // from the last scheduled write, returned by the next change
+}
+
+type segment struct {
+	path  string
+	first uint64
+	last  uint64 // first-1 while the segment is empty
+	size  int64
+}
+
+// consumer is the delivery state of one durable listener.
+type consumer struct {
+	acked uint64          // every event up to here was handled
+	saved uint64          // acked as last written to disk
+	done  map[uint64]bool // handled events above acked, waiting for the gap to close
+	dead  []outboxRecord  // failed events, by offset, for redelivery on registration
+	owned bool            // claimed by OnDurable in this process
+	live  bool            // receiving live events, so settle counts it
+}
+
+// consumerState is a consumer as stored in consumers.json.
+type consumerState struct {
+	Acked uint64         `json:"acked"`
+	Dead  []outboxRecord `json:"dead,omitempty"`
+}
+
+type outboxRecord struct {
+	Offset    uint64
+	EventName string
+	Timestamp time.Time
+	Data      []byte
+}
+
+// OpenOutbox opens the outbox in dir, creating it if needed. Segments are
+// scanned to find the next offset; a torn frame at the end of the newest
+// segment, left by a crash during a write, is truncated away.
+func OpenOutbox(dir string, opts ...OutboxOption) (*Outbox, error) {
+	o := outboxOptions{segmentSize: defaultSegmentSize, codec: GobCodec{}, ackInterval: defaultAckInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("emitter: creating outbox: %w", err)
+	}
+
+	outbox := &Outbox{
+		dir:         dir,
+		codec:       o.codec,
+		segmentSize: o.segmentSize,
+		ackInterval: o.ackInterval,
+		consumers:   make(map[string]*consumer),
+	}
+	if err := outbox.loadConsumers(); err != nil {
+		return nil, err
+	}
+	if err := outbox.loadSegments(); err != nil {
+		return nil, err
+	}
+	return outbox, nil
+}
+
+// loadConsumers reads the acknowledged offsets. An unreadable file is not
+// an error: redelivering everything is safe, refusing to start is not.
+func (o *Outbox) loadConsumers() error {
+	data, err := os.ReadFile(filepath.Join(o.dir, consumersFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	states := map[string]consumerState{}
+	if err == nil {
+		err = json.Unmarshal(data, &states)
+	}
+	if err != nil {
+		o.lostAcks = true
+		return nil
+	}
+	for name, state := range states {
+		o.consumers[name] = &consumer{
+			acked: state.Acked,
+			saved: state.Acked,
+			done:  make(map[uint64]bool),
+			dead:  state.Dead,
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) loadSegments() error {
+	paths, err := filepath.Glob(filepath.Join(o.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		first, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			return fmt.Errorf("emitter: unexpected outbox file %s", path)
+		}
+		o.segments = append(o.segments, &segment{path: path, first: first, last: first - 1})
+	}
+	slices.SortFunc(o.segments, func(a, b *segment) int {
+		return cmp.Compare(a.first, b.first)
+	})
+
+	for i, seg := range o.segments {
+		newest := i == len(o.segments)-1
+		size, err := seg.scan(newest)
+		if err != nil {
+			return err
+		}
+		seg.size = size
+	}
+
+	if len(o.segments) == 0 {
+		// Start after anything a consumer already acknowledged
+		first := uint64(1)
+		for _, c := range o.consumers {
+			first = max(first, c.acked+1)
+		}
+		return o.startSegment(first)
+	}
+
+	active := o.segments[len(o.segments)-1]
+	o.nextOffset = active.last + 1
+	f, err := os.OpenFile(active.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("emitter: opening outbox segment: %w", err)
+	}
+	o.active = f
+	return nil
+}
+
+// scan reads the segment to find its last offset and returns its valid size.
+// In the newest segment a torn tail is truncated; elsewhere it is an error.
+func (s *segment) scan(newest bool) (int64, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return 0, fmt.Errorf("emitter: opening outbox segment: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var valid int64
+	for {
+		record, n, err := readFrame(r)
+		if err == io.EOF {
+			return valid, nil
+		}
+		if errors.Is(err, errTornFrame) {
+			if !newest {
+				return 0, fmt.Errorf("emitter: outbox segment %s is corrupt at byte %d", s.path, valid)
+			}
+			if err := os.Truncate(s.path, valid); err != nil {
+				return 0, fmt.Errorf("emitter: truncating torn outbox segment: %w", err)
+			}
+			return valid, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		s.last = record.Offset
+		valid += n
+	}
+}
+
+// startSegment creates an empty segment for offsets from first on and makes
+// it the active one. Callers must hold o.mu or own o exclusively.
+func (o *Outbox) startSegment(first uint64) error {
+	path := filepath.Join(o.dir, fmt.Sprintf("%020d%s", first, segmentExt))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("emitter: creating outbox segment: %w", err)
+	}
+	if err := syncDir(o.dir); err != nil {
+		f.Close()
+		return fmt.Errorf("emitter: creating outbox segment: %w", err)
+	}
+	if o.active != nil {
+		o.active.Close()
+	}
+	o.active = f
+	o.segments = append(o.segments, &segment{path: path, first: first, last: first - 1})
+	o.nextOffset = first
+	return nil
+}
+
+// append writes an event and syncs it to disk. Returns its offset.
+func (o *Outbox) append(eventName string, data interface{}) (uint64, error) {
+	encoded, err := o.codec.Encode(data)
+	if err != nil {
+		return 0, fmt.Errorf("emitter: encoding %q for the outbox: %w", eventName, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return 0, ErrOutboxClosed
+	}
+
+	offset := o.nextOffset
+	frame, err := encodeFrame(outboxRecord{Offset: offset, EventName: eventName, Timestamp: time.Now(), Data: encoded})
+	if err != nil {
+		return 0, err
+	}
+
+	active := o.segments[len(o.segments)-1]
+	if _, err := o.active.Write(frame); err != nil {
+		// Cut off a partial frame so later writes stay readable
+		o.active.Truncate(active.size)
+		return 0, fmt.Errorf("emitter: writing to outbox: %w", err)
+	}
+	if err := o.active.Sync(); err != nil {
+		return 0, fmt.Errorf("emitter: syncing outbox: %w", err)
+	}
+	active.last = offset
+	active.size += int64(len(frame))
+	o.nextOffset++
+
+	// A failed rotation keeps appending to the current segment
+	if active.size >= o.segmentSize && o.startSegment(o.nextOffset) == nil && !o.lostAcks {
+		o.compact()
+	}
+	return offset, nil
+}
+
+func encodeFrame(record outboxRecord) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("emitter: encoding outbox record: %w", err)
+	}
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	copy(frame[frameHeaderSize:], payload)
+	return frame, nil
+}
+
+// readFrame reads one frame and returns its record and size. It returns
+// io.EOF at a clean end and errTornFrame for a partial or corrupt frame.
+func readFrame(r io.Reader) (outboxRecord, int64, error) {
+	var record outboxRecord
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return record, 0, io.EOF
+		}
+		return record, 0, errTornFrame
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return record, 0, errTornFrame
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return record, 0, errTornFrame
+	}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, 0, errTornFrame
+	}
+	return record, int64(frameHeaderSize + len(payload)), nil
+}
+
+// read calls fn with every event after offset after, up to and including
+// offset until, in order.
+func (o *Outbox) read(after, until uint64, fn func(offset uint64, eventName string, data interface{})) error {
+	o.mu.Lock()
+	segments := []segment{}
+	for _, seg := range o.segments {
+		if seg.last > after && seg.first <= until {
+			segments = append(segments, *seg)
+		}
+	}
+	o.mu.Unlock()
+
+	for _, seg := range segments {
+		if err := o.readSegment(seg, after, until, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) readSegment(seg segment, after, until uint64, fn func(offset uint64, eventName string, data interface{})) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return fmt.Errorf("emitter: opening outbox segment: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(io.LimitReader(f, seg.size))
+	for {
+		record, _, err := readFrame(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("emitter: reading outbox segment %s: %w", seg.path, err)
+		}
+		if record.Offset > until {
+			return nil
+		}
+		if record.Offset <= after {
+			continue
+		}
+		data, err := o.codec.Decode(record.Data)
+		if err != nil {
+			return fmt.Errorf("emitter: decoding outbox event %d: %w", record.Offset, err)
+		}
+		fn(record.Offset, record.EventName, data)
+	}
+}
+
+// claim reserves a durable listener name for this process, creating the
+// listener at the current end of the outbox if it is new.
+func (o *Outbox) claim(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c, exists := o.consumers[name]
+	if exists && c.owned {
+		return fmt.Errorf("emitter: durable listener %q is already registered", name)
+	}
+	if !exists {
+		acked := o.nextOffset - 1
+		if o.lostAcks {
+			acked = 0
+		}
+		c = &consumer{acked: acked, saved: acked, done: make(map[uint64]bool)}
+		o.consumers[name] = c
+		if err := o.saveConsumers(); err != nil {
+			delete(o.consumers, name)
+			return err
+		}
+	}
+	c.owned = true
+	return nil
+}
+
+// activate starts counting a claimed listener in settle, once it receives
+// live events. Returns the offset it has acknowledged and the last offset
+// written so far; the events in between are its backlog.
+func (o *Outbox) activate(name string) (acked, last uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c := o.consumers[name]
+	c.live = true
+	return c.acked, o.nextOffset - 1
+}
+
+// release gives up a claimed name. The acknowledged offset is kept for when
+// the listener registers again.
+func (o *Outbox) release(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if c, exists := o.consumers[name]; exists {
+		c.owned = false
+		c.live = false
+	}
+}
+
+// ack records that a durable listener handled the event at offset.
+func (o *Outbox) ack(name string, offset uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c, exists := o.consumers[name]
+	if !exists || !c.markDone(offset) {
+		return nil
+	}
+	return o.changed()
+}
+
+// deadLetter records that a durable listener failed the event at offset,
+// keeping the event for redelivery, and counts it as handled.
+func (o *Outbox) deadLetter(name string, offset uint64, eventName string, data interface{}) error {
+	encoded, err := o.codec.Encode(data)
+	if err != nil {
+		return fmt.Errorf("emitter: encoding %q for the outbox: %w", eventName, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c, exists := o.consumers[name]
+	if !exists || offset <= c.acked || c.done[offset] {
+		return nil
+	}
+	c.dead = append(c.dead, outboxRecord{Offset: offset, EventName: eventName, Timestamp: time.Now(), Data: encoded})
+	slices.SortFunc(c.dead, func(a, b outboxRecord) int { return cmp.Compare(a.Offset, b.Offset) })
+	c.markDone(offset)
+	return o.changed()
+}
+
+// deadLetters calls fn with the named listener's dead letters, in order, and
+// drops those for which it returns true.
+func (o *Outbox) deadLetters(name string, fn func(offset uint64, eventName string, data interface{}) bool) error {
+	o.mu.Lock()
+	var dead []outboxRecord
+	if c, exists := o.consumers[name]; exists {
+		dead = slices.Clone(c.dead)
+	}
+	o.mu.Unlock()
+
+	for _, record := range dead {
+		data, err := o.codec.Decode(record.Data)
+		if err != nil {
+			return fmt.Errorf("emitter: decoding outbox event %d: %w", record.Offset, err)
+		}
+		if !fn(record.Offset, record.EventName, data) {
+			continue
+		}
+
+		o.mu.Lock()
+		c, exists := o.consumers[name]
+		if exists {
+			c.dead = slices.DeleteFunc(c.dead, func(r outboxRecord) bool { return r.Offset == record.Offset })
+			err = o.changed()
+		}
+		o.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle records the event at offset as handled by every live durable
+// listener except those still working on it or that failed it.
+func (o *Outbox) settle(offset uint64, unsettled map[string]bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	advanced := false
+	for name, c := range o.consumers {
+		if c.live && !unsettled[name] && c.markDone(offset) {
+			advanced = true
+		}
+	}
+	if !advanced {
+		return nil
+	}
+	return o.changed()
+}
+
+// markDone adds offset to the handled events and reports whether acked moved.
+func (c *consumer) markDone(offset uint64) bool {
+	if offset <= c.acked {
+		return false
+	}
+	c.done[offset] = true
+	advanced := false
+	for c.done[c.acked+1] {
+		delete(c.done, c.acked+1)
+		c.acked++
+		advanced = true
+	}
+	return advanced
+}
+
+// changed schedules writing the consumer state, or writes it at once
+// without an ack interval. Returns the error of a scheduled write that
+// failed since the last change. Callers must hold o.mu.
+func (o *Outbox) changed() error {
+	if o.ackInterval == 0 {
+		return o.saveConsumers()
+	}
+	o.dirty = true
+	if o.flushTimer == nil && !o.closed {
+		o.flushTimer = time.AfterFunc(o.ackInterval, o.flush)
+	}
+	err := o.flushErr
+	o.flushErr = nil
+	return err
+}
+
+// flush writes the consumer state if it changed since it was last written.
+func (o *Outbox) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.flushTimer = nil
+	if o.dirty && !o.closed {
+		o.flushErr = o.saveConsumers()
+	}
+}
+
+// saveConsumers writes the consumer state atomically and durably: a crash
+// leaves either the old or the new file. Callers must hold o.mu.
+func (o *Outbox) saveConsumers() error {
+	states := make(map[string]consumerState, len(o.consumers))
+	for name, c := range o.consumers {
+		states[name] = consumerState{Acked: c.acked, Dead: c.dead}
+	}
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(o.dir, consumersFile)
+	tmp := path + ".tmp"
+	if err := writeSynced(tmp, data); err != nil {
+		return fmt.Errorf("emitter: saving outbox consumers: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("emitter: saving outbox consumers: %w", err)
+	}
+	if err := syncDir(o.dir); err != nil {
+		return fmt.Errorf("emitter: saving outbox consumers: %w", err)
+	}
+	for _, c := range o.consumers {
+		c.saved = c.acked
+	}
+	o.dirty = false
+	return nil
+}
+
+// writeSynced writes data to a file and syncs it before closing.
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir syncs a directory, so the files created or renamed in it survive
+// a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Acked returns the offset up to which the named durable listener has
+// handled every event, and whether the outbox knows the listener.
+func (o *Outbox) Acked(name string) (uint64, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c, exists := o.consumers[name]
+	if !exists {
+		return 0, false
+	}
+	return c.acked, true
+}
+
+// LastOffset returns the offset of the newest event, 0 if none was written.
+func (o *Outbox) LastOffset() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.nextOffset - 1
+}
+
+// DeadLetters returns the offsets of the events the named durable listener
+// failed and has not handled since, oldest first.
+func (o *Outbox) DeadLetters(name string) []uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c, exists := o.consumers[name]
+	if !exists {
+		return nil
+	}
+	offsets := make([]uint64, len(c.dead))
+	for i, record := range c.dead {
+		offsets[i] = record.Offset
+	}
+	return offsets
+}
+
+// Forget drops a durable listener that will not come back, so its backlog
+// and dead letters no longer hold up compaction.
+func (o *Outbox) Forget(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.consumers, name)
+	return o.saveConsumers()
+}
+
+// Compact removes the segments whose events every durable listener has
+// acknowledged, as written to disk. The segment being written to is always
+// kept.
+// After consumers.json was lost, only the listeners registered since count:
+// call Compact once every durable listener that will come back has; then
+// automatic compaction resumes and new listeners start at the end again.
+// Returns the number of segments removed.
+func (o *Outbox) Compact() (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.lostAcks = false
+	return o.compact()
+}
+
+// compact is Compact for callers that hold o.mu.
+func (o *Outbox) compact() (int, error) {
+	low := o.nextOffset - 1
+	for _, c := range o.consumers {
+		low = min(low, c.saved)
+	}
+
+	removed := 0
+	for len(o.segments) > 1 && o.segments[0].last <= low {
+		if err := os.Remove(o.segments[0].path); err != nil {
+			return removed, fmt.Errorf("emitter: removing outbox segment: %w", err)
+		}
+		o.segments = o.segments[1:]
+		removed++
+	}
+	return removed, nil
+}
+
+// Segments returns the number of segment files, including the active one.
+func (o *Outbox) Segments() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.segments)
+}
+
+// Close writes pending acknowledgements and closes the outbox; every event
+// was already synced when written. Close the emitter using it first.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+	var err error
+	if o.flushTimer != nil {
+		o.flushTimer.Stop()
+		o.flushTimer = nil
+	}
+	if o.dirty {
+		err = o.saveConsumers()
+	}
+	o.closed = true
+	return errors.Join(err, o.active.Close())
+}
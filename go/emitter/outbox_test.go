@@ -0,0 +1,260 @@
+package emitter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openOutbox opens the outbox in dir or fails the test.
+func openOutbox(t *testing.T, dir string, opts ...OutboxOption) *Outbox {
+	t.Helper()
+	o, err := OpenOutbox(dir, opts...)
+	if err != nil {
+		t.Fatalf("OpenOutbox: %v", err)
+	}
+	return o
+}
+
+// newestSegment returns the path of the segment being appended to.
+func newestSegment(t *testing.T, dir string) string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no segments in %s: %v", dir, err)
+	}
+	return paths[len(paths)-1]
+}
+
+// readAll returns the event names in the outbox, in offset order.
+func readAll(t *testing.T, o *Outbox) []string {
+	t.Helper()
+	names := []string{}
+	err := o.read(0, o.LastOffset(), func(offset uint64, eventName string, data interface{}) {
+		names = append(names, eventName)
+	})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return names
+}
+
+func TestOutboxAppendAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir)
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := o.append(name, nil); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if o.LastOffset() != 3 {
+		t.Errorf("expected last offset 3, got %d", o.LastOffset())
+	}
+	o.Close()
+
+	o = openOutbox(t, dir)
+	defer o.Close()
+	if o.LastOffset() != 3 {
+		t.Errorf("expected last offset 3 after reopen, got %d", o.LastOffset())
+	}
+	if offset, _ := o.append("d", nil); offset != 4 {
+		t.Errorf("expected offset 4 after reopen, got %d", offset)
+	}
+	if got := readAll(t, o); !reflect.DeepEqual(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("unexpected events %v", got)
+	}
+}
+
+func TestOutboxTruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir)
+	o.append("a", nil)
+	o.append("b", nil)
+	// Crash without Close, halfway through writing a frame
+	frame, _ := encodeFrame(outboxRecord{Offset: 3, EventName: "c"})
+	f, _ := os.OpenFile(newestSegment(t, dir), os.O_WRONLY|os.O_APPEND, 0o644)
+	f.Write(frame[:len(frame)/2])
+	f.Close()
+
+	o = openOutbox(t, dir)
+	defer o.Close()
+	if o.LastOffset() != 2 {
+		t.Errorf("expected the torn frame to be dropped, last offset %d", o.LastOffset())
+	}
+	if offset, _ := o.append("c", nil); offset != 3 {
+		t.Errorf("expected offset 3 after the torn frame, got %d", offset)
+	}
+	if got := readAll(t, o); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected events %v", got)
+	}
+}
+
+func TestOutboxTruncatesCorruptTail(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir)
+	o.append("a", nil)
+	o.append("b", nil)
+	o.Close()
+
+	// Flip a payload byte of the last frame so its CRC no longer matches
+	path := newestSegment(t, dir)
+	data, _ := os.ReadFile(path)
+	data[len(data)-2] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	o = openOutbox(t, dir)
+	defer o.Close()
+	if got := readAll(t, o); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("expected only the intact event, got %v", got)
+	}
+}
+
+func TestOutboxCorruptOlderSegment(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir, WithSegmentSize(1))
+	o.claim("c") // keeps segments from being compacted
+	o.append("a", nil)
+	o.append("b", nil)
+	o.Close()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	data, _ := os.ReadFile(paths[0])
+	data[len(data)-2] ^= 0xff
+	os.WriteFile(paths[0], data, 0o644)
+
+	if _, err := OpenOutbox(dir); err == nil {
+		t.Error("expected an error for a corrupt segment that is not the newest")
+	}
+}
+
+func TestOutboxCompactKeepsUnacknowledgedSegments(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir, WithSegmentSize(1), WithAckInterval(0))
+	o.claim("fast")
+	o.activate("fast")
+	o.claim("slow")
+	o.activate("slow")
+
+	for i := 0; i < 5; i++ {
+		offset, _ := o.append("event", i)
+		o.settle(offset, map[string]bool{"slow": true})
+	}
+	// One event per segment, plus the empty active one; slow holds them all
+	if o.Segments() != 6 {
+		t.Fatalf("expected 6 segments, got %d", o.Segments())
+	}
+
+	o.ack("slow", 1)
+	o.ack("slow", 2)
+	if removed, _ := o.Compact(); removed != 2 {
+		t.Errorf("expected 2 segments removed, got %d", removed)
+	}
+
+	o.Forget("slow")
+	if removed, _ := o.Compact(); removed != 3 || o.Segments() != 1 {
+		t.Errorf("expected only the active segment left, removed %d, %d left", removed, o.Segments())
+	}
+	o.Close()
+
+	// Offsets carry on after every written segment was compacted away
+	o = openOutbox(t, dir)
+	defer o.Close()
+	if offset, _ := o.append("event", nil); offset != 6 {
+		t.Errorf("expected offset 6, got %d", offset)
+	}
+	if acked, _ := o.Acked("fast"); acked != 5 {
+		t.Errorf("expected fast to have acknowledged 5, got %d", acked)
+	}
+}
+
+func TestOutboxAcksInOrder(t *testing.T) {
+	o := openOutbox(t, t.TempDir())
+	defer o.Close()
+	o.claim("c")
+	for i := 0; i < 3; i++ {
+		o.append("event", nil)
+	}
+
+	// Acknowledged out of order, acked only moves past contiguous offsets
+	o.ack("c", 2)
+	o.ack("c", 3)
+	if acked, _ := o.Acked("c"); acked != 0 {
+		t.Errorf("expected acked 0 while offset 1 is open, got %d", acked)
+	}
+	o.ack("c", 1)
+	if acked, _ := o.Acked("c"); acked != 3 {
+		t.Errorf("expected acked 3, got %d", acked)
+	}
+}
+
+func TestOutboxBatchesAckWrites(t *testing.T) {
+	dir := t.TempDir()
+	o := openOutbox(t, dir, WithSegmentSize(1), WithAckInterval(time.Hour))
+	o.claim("c")
+	for i := 0; i < 3; i++ {
+		offset, _ := o.append("event", i)
+		o.ack("c", offset)
+	}
+
+	// Not written yet, so compaction must not trust it
+	if acked, _ := o.Acked("c"); acked != 3 {
+		t.Errorf("expected acked 3 in memory, got %d", acked)
+	}
+	if removed, _ := o.Compact(); removed != 0 {
+		t.Errorf("expected nothing compacted before the write, removed %d", removed)
+	}
+	crashed := openOutbox(t, dir)
+	if acked, _ := crashed.Acked("c"); acked != 0 {
+		t.Errorf("expected acked 0 on disk before the write, got %d", acked)
+	}
+	crashed.Close()
+
+	o.flush()
+	if removed, _ := o.Compact(); removed != 3 {
+		t.Errorf("expected 3 segments compacted once written, removed %d", removed)
+	}
+	// Close writes what is still pending
+	offset, _ := o.append("event", 3)
+	o.ack("c", offset)
+	o.Close()
+	o = openOutbox(t, dir)
+	defer o.Close()
+	if acked, _ := o.Acked("c"); acked != 4 {
+		t.Errorf("expected acked 4 after reopening, got %d", acked)
+	}
+}
+
+func TestOutboxCodecs(t *testing.T) {
+	tests := []struct {
+		codec Codec
+		want  interface{}
+	}{
+		{GobCodec{}, 42},
+		{JSONCodec{}, float64(42)},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		o := openOutbox(t, dir, WithCodec(tt.codec))
+		o.append("event", 42)
+		o.Close()
+
+		o = openOutbox(t, dir, WithCodec(tt.codec))
+		var got interface{}
+		o.read(0, 1, func(offset uint64, eventName string, data interface{}) { got = data })
+		o.Close()
+		if got != tt.want {
+			t.Errorf("%T: expected %#v, got %#v", tt.codec, tt.want, got)
+		}
+	}
+}
+
+func TestOutboxClosed(t *testing.T) {
+	o := openOutbox(t, t.TempDir())
+	o.Close()
+	if _, err := o.append("event", nil); !errors.Is(err, ErrOutboxClosed) {
+		t.Errorf("expected ErrOutboxClosed, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package emitter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format, for serving from a /metrics handler:
+//
+//	emitter_events_emitted_total       counter    event
+//	emitter_event_errors_total         counter    event
+//	emitter_event_latency_seconds      histogram  event
+//	emitter_listener_calls_total       counter    listener, pattern
+//	emitter_listener_errors_total      counter    listener, pattern
+//	emitter_listener_slow_calls_total  counter    listener, pattern
+//	emitter_listener_latency_seconds   histogram  listener, pattern
+//	emitter_listener_queue_depth       gauge      listener, pattern
+func (m Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	names := make([]string, 0, len(m.Events))
+	for name := range m.Events {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	writeHeader(bw, "emitter_events_emitted_total", "counter", "Events emitted, by event name.")
+	for _, name := range names {
+		writeSample(bw, "emitter_events_emitted_total", eventLabels(name), strconv.FormatUint(m.Events[name].Emitted, 10))
+	}
+	writeHeader(bw, "emitter_event_errors_total", "counter", "Listener failures, by event name.")
+	for _, name := range names {
+		writeSample(bw, "emitter_event_errors_total", eventLabels(name), strconv.FormatUint(m.Events[name].Errors, 10))
+	}
+	writeHeader(bw, "emitter_event_latency_seconds", "histogram", "Time spent dispatching events, by event name.")
+	for _, name := range names {
+		writeHistogram(bw, "emitter_event_latency_seconds", eventLabels(name), m.Events[name].Latency)
+	}
+
+	writeHeader(bw, "emitter_listener_calls_total", "counter", "Listener calls.")
+	for _, l := range m.Listeners {
+		writeSample(bw, "emitter_listener_calls_total", listenerLabels(l), strconv.FormatUint(l.Calls, 10))
+	}
+	writeHeader(bw, "emitter_listener_errors_total", "counter", "Listener failures.")
+	for _, l := range m.Listeners {
+		writeSample(bw, "emitter_listener_errors_total", listenerLabels(l), strconv.FormatUint(l.Errors, 10))
+	}
+	writeHeader(bw, "emitter_listener_slow_calls_total", "counter", "Listener calls slower than the slow-listener threshold.")
+	for _, l := range m.Listeners {
+		writeSample(bw, "emitter_listener_slow_calls_total", listenerLabels(l), strconv.FormatUint(l.SlowCalls, 10))
+	}
+
+	writeHeader(bw, "emitter_listener_latency_seconds", "histogram", "Time spent in listener calls.")
+	for _, l := range m.Listeners {
+		writeHistogram(bw, "emitter_listener_latency_seconds", listenerLabels(l), l.Latency)
+	}
+
+	writeHeader(bw, "emitter_listener_queue_depth", "gauge", "Events waiting in the listener's EmitAsync queue.")
+	for _, l := range m.Listeners {
+		writeSample(bw, "emitter_listener_queue_depth", listenerLabels(l), strconv.Itoa(l.QueueDepth))
+	}
+
+	return bw.Flush()
+}
+
+func writeHeader(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeSample(w *bufio.Writer, name, labels, value string) {
+	fmt.Fprintf(w, "%s{%s} %s\n", name, labels, value)
+}
+
+// writeHistogram writes the cumulative buckets, sum and count of h.
+func writeHistogram(w *bufio.Writer, name, labels string, h Histogram) {
+	var cumulative uint64
+	for i, count := range h.Counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(h.Bounds) {
+			le = formatSeconds(h.Bounds[i])
+		}
+		writeSample(w, name+"_bucket", labels+`,le="`+le+`"`, strconv.FormatUint(cumulative, 10))
+	}
+	writeSample(w, name+"_sum", labels, formatSeconds(h.Sum))
+	writeSample(w, name+"_count", labels, strconv.FormatUint(h.Count, 10))
+}
+
+func eventLabels(eventName string) string {
+	return `event="` + escapeLabel(eventName) + `"`
+}
+
+func listenerLabels(l ListenerMetrics) string {
+	return `listener="` + escapeLabel(l.ListenerID) + `",pattern="` + escapeLabel(l.EventName) + `"`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
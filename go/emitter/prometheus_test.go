@@ -0,0 +1,113 @@
+package emitter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	m := Metrics{
+		Events: map[string]EventMetrics{
+			"b": {Emitted: 1, Latency: Histogram{
+				Bounds: []time.Duration{time.Millisecond},
+				Counts: []uint64{1, 0},
+				Count:  1,
+				Sum:    250 * time.Microsecond,
+			}},
+			"a": {Emitted: 3, Errors: 1, Latency: Histogram{
+				Bounds: []time.Duration{time.Millisecond},
+				Counts: []uint64{0, 0},
+			}},
+		},
+		Listeners: []ListenerMetrics{{
+			ListenerID: "L-1",
+			EventName:  "a",
+			Calls:      3,
+			Errors:     1,
+			SlowCalls:  1,
+			Latency: Histogram{
+				Bounds: []time.Duration{time.Millisecond, 500 * time.Millisecond},
+				Counts: []uint64{1, 1, 1},
+				Count:  3,
+				Sum:    1500 * time.Millisecond,
+			},
+			QueueDepth: 2,
+		}},
+	}
+
+	var out strings.Builder
+	if err := m.WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+
+	want := `# HELP emitter_events_emitted_total Events emitted, by event name.
+# TYPE emitter_events_emitted_total counter
+emitter_events_emitted_total{event="a"} 3
+emitter_events_emitted_total{event="b"} 1
+# HELP emitter_event_errors_total Listener failures, by event name.
+# TYPE emitter_event_errors_total counter
+emitter_event_errors_total{event="a"} 1
+emitter_event_errors_total{event="b"} 0
+# HELP emitter_event_latency_seconds Time spent dispatching events, by event name.
+# TYPE emitter_event_latency_seconds histogram
+emitter_event_latency_seconds_bucket{event="a",le="0.001"} 0
+emitter_event_latency_seconds_bucket{event="a",le="+Inf"} 0
+emitter_event_latency_seconds_sum{event="a"} 0
+emitter_event_latency_seconds_count{event="a"} 0
+emitter_event_latency_seconds_bucket{event="b",le="0.001"} 1
+emitter_event_latency_seconds_bucket{event="b",le="+Inf"} 1
+emitter_event_latency_seconds_sum{event="b"} 0.00025
+emitter_event_latency_seconds_count{event="b"} 1
+# HELP emitter_listener_calls_total Listener calls.
+# TYPE emitter_listener_calls_total counter
+emitter_listener_calls_total{listener="L-1",pattern="a"} 3
+# HELP emitter_listener_errors_total Listener failures.
+# TYPE emitter_listener_errors_total counter
+emitter_listener_errors_total{listener="L-1",pattern="a"} 1
+# HELP emitter_listener_slow_calls_total Listener calls slower than the slow-listener threshold.
+# TYPE emitter_listener_slow_calls_total counter
+emitter_listener_slow_calls_total{listener="L-1",pattern="a"} 1
+# HELP emitter_listener_latency_seconds Time spent in listener calls.
+# TYPE emitter_listener_latency_seconds histogram
+emitter_listener_latency_seconds_bucket{listener="L-1",pattern="a",le="0.001"} 1
+emitter_listener_latency_seconds_bucket{listener="L-1",pattern="a",le="0.5"} 2
+emitter_listener_latency_seconds_bucket{listener="L-1",pattern="a",le="+Inf"} 3
+emitter_listener_latency_seconds_sum{listener="L-1",pattern="a"} 1.5
+emitter_listener_latency_seconds_count{listener="L-1",pattern="a"} 3
+# HELP emitter_listener_queue_depth Events waiting in the listener's EmitAsync queue.
+# TYPE emitter_listener_queue_depth gauge
+emitter_listener_queue_depth{listener="L-1",pattern="a"} 2
+`
+	if out.String() != want {
+		t.Errorf("unexpected exposition:\n%s", out.String())
+	}
+}
+
+func TestWritePrometheusEscapesLabels(t *testing.T) {
+	m := Metrics{Events: map[string]EventMetrics{"a\"b\\c\nd": {Emitted: 1}}}
+
+	var out strings.Builder
+	m.WritePrometheus(&out)
+	if !strings.Contains(out.String(), `{event="a\"b\\c\nd"} 1`) {
+		t.Errorf("expected escaped label, got:\n%s", out.String())
+	}
+}
+
+func TestWritePrometheusFromEmitter(t *testing.T) {
+	e := NewEventEmitter()
+	e.On("user.*", func(data interface{}) {})
+	e.Emit("user.created", nil)
+
+	var out strings.Builder
+	e.Metrics().WritePrometheus(&out)
+	for _, line := range []string{
+		`emitter_events_emitted_total{event="user.created"} 1`,
+		`emitter_listener_calls_total{listener="L-1",pattern="user.*"} 1`,
+		`emitter_listener_latency_seconds_count{listener="L-1",pattern="user.*"} 1`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, out.String())
+		}
+	}
+}
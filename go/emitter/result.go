@@ -0,0 +1,56 @@
+package emitter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStopPropagation can be returned by a listener registered with OnErr to
+// keep Emit from calling the listeners after it. It is not a failure.
+var ErrStopPropagation = errors.New("emitter: stop propagation")
+
+// ListenerError is a failure of one listener while handling an event:
+// an error returned by a listener registered with OnErr, or a recovered panic.
+type ListenerError struct {
+	ListenerID string
+	EventName  string
+	Err        error
+}
+
+func (le ListenerError) Error() string {
+	return fmt.Sprintf("emitter: listener %s failed on %q: %v", le.ListenerID, le.EventName, le.Err)
+}
+
+func (le ListenerError) Unwrap() error {
+	return le.Err
+}
+
+// PanicError is the Err of a ListenerError whose listener panicked.
+type PanicError struct {
+	Value interface{} // the value passed to panic
+	Stack []byte      // the listener's stack at the time of the panic
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", pe.Value)
+}
+
+// EmitResult reports what happened to an emitted event.
+type EmitResult struct {
+	Delivered    int             // listeners called, including those that failed
+	Errors       []ListenerError // failed listeners, in call order
+	PersistError error           // the outbox could not record the event, so it was not dispatched
+}
+
+// Err joins the persist and listener errors, or returns nil if the event
+// was recorded and every listener succeeded.
+func (r EmitResult) Err() error {
+	if r.PersistError == nil && len(r.Errors) == 0 {
+		return nil
+	}
+	errs := []error{r.PersistError}
+	for _, le := range r.Errors {
+		errs = append(errs, le)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package emitter
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEmitRecoversPanics(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := 0
+	e.On("event", func(data interface{}) { calls++ })
+	panicID := e.Once("event", func(data interface{}) { panic("boom") })
+	e.On("event", func(data interface{}) { calls++ })
+
+	result := e.Emit("event", nil)
+	if result.Delivered != 3 {
+		t.Errorf("expected 3 listeners called, got %d", result.Delivered)
+	}
+	if calls != 2 {
+		t.Errorf("listeners after the panicking one should still run, got %d calls", calls)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].ListenerID != panicID {
+		t.Fatalf("expected the panic to be reported for %s, got %+v", panicID, result.Errors)
+	}
+
+	var panicErr *PanicError
+	if !errors.As(result.Err(), &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("expected a PanicError with value and stack, got %v", result.Err())
+	}
+
+	// The panicking Once listener must not stay registered
+	if e.ListenerCount("event") != 2 {
+		t.Errorf("once listener should be removed despite panicking, have %d listeners", e.ListenerCount("event"))
+	}
+}
+
+func TestOnErr(t *testing.T) {
+	e := NewEventEmitter()
+	errInvalid := errors.New("invalid order")
+
+	id := e.OnErr("order.*", func(data interface{}) error {
+		if data == nil {
+			return errInvalid
+		}
+		return nil
+	})
+
+	if result := e.Emit("order.created", "ok"); result.Err() != nil || result.Delivered != 1 {
+		t.Errorf("expected a clean delivery, got %+v", result)
+	}
+
+	result := e.Emit("order.created", nil)
+	if !errors.Is(result.Err(), errInvalid) {
+		t.Errorf("returned error should be reported, got %v", result.Err())
+	}
+	le := result.Errors[0]
+	if le.ListenerID != id || le.EventName != "order.created" {
+		t.Errorf("error should name the listener and the emitted event, got %+v", le)
+	}
+	if !strings.Contains(le.Error(), id) || !strings.Contains(le.Error(), "invalid order") {
+		t.Errorf("unexpected error message %q", le.Error())
+	}
+}
+
+func TestOnceErr(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := 0
+	e.OnceErr("event", func(data interface{}) error {
+		calls++
+		return errors.New("boom")
+	})
+
+	e.Emit("event", nil)
+	e.Emit("event", nil)
+	if calls != 1 || e.ListenerCount("event") != 0 {
+		t.Error("OnceErr listener should fire once and be removed, even when failing")
+	}
+}
+
+func TestEventLogRecordsFailures(t *testing.T) {
+	e := NewEventEmitter()
+
+	e.On("event", func(data interface{}) {})
+	e.OnErr("event", func(data interface{}) error { return errors.New("boom") })
+
+	e.Emit("event", "data")
+	e.Emit("other", "data")
+
+	logs := e.GetEventLogs()
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 logs, got %d", len(logs))
+	}
+	if logs[0].Listeners != 2 || len(logs[0].Errors) != 1 {
+		t.Errorf("log should record 2 listeners and 1 failure, got %+v", logs[0])
+	}
+	if len(logs[1].Errors) != 0 {
+		t.Error("log without failures should have no errors")
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	var mu sync.Mutex
+	failures := []ListenerError{}
+	e := NewEventEmitter(WithErrorHandler(func(le ListenerError) {
+		mu.Lock()
+		failures = append(failures, le)
+		mu.Unlock()
+	}))
+
+	e.On("event", func(data interface{}) { panic("async boom") })
+	e.OnErr("event", func(data interface{}) error { return errors.New("async error") })
+
+	e.EmitAsync("event", nil)
+	e.Close()
+
+	if len(failures) != 2 {
+		t.Fatalf("async failures should reach the handler, got %d", len(failures))
+	}
+
+	e.Emit("event", nil)
+	if len(failures) != 4 {
+		t.Errorf("sync failures should reach the handler too, got %d", len(failures))
+	}
+}
+
+func TestEmitAsyncSurvivesPanics(t *testing.T) {
+	e := NewEventEmitter()
+
+	var mu sync.Mutex
+	received := 0
+	e.On("event", func(data interface{}) {
+		if data == 1 {
+			panic("boom")
+		}
+		mu.Lock()
+		received++
+		mu.Unlock()
+	})
+
+	for i := 0; i < 3; i++ {
+		e.EmitAsync("event", i)
+	}
+	e.Close()
+
+	if received != 2 {
+		t.Errorf("the listener's worker should keep going after a panic, got %d events", received)
+	}
+}
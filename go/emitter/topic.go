@@ -0,0 +1,59 @@
+package emitter
+
+import "fmt"
+
+// Topic is a typed handle for one event name on an EventEmitter. Declaring
+// a topic once, e.g.
+//
+//	var OrderCreated = emitter.NewTopic[Order](bus, "order.created")
+//
+// lets the compiler check payload types and event names on both sides.
+// Topics share the emitter's listeners, event log and Off: Subscribe returns
+// a regular listener ID, and untyped On/Emit calls for the same name interoperate.
+type Topic[T any] struct {
+	emitter *EventEmitter
+	name    string
+}
+
+func NewTopic[T any](e *EventEmitter, name string) *Topic[T] {
+	return &Topic[T]{emitter: e, name: name}
+}
+
+// Name returns the event name the topic publishes and subscribes to.
+func (t *Topic[T]) Name() string {
+	return t.name
+}
+
+// Subscribe registers callback for the topic's events.
+// A payload that is not a T, emitted on the same name through the untyped
+// API, is not passed to callback but reported as a listener error.
+// Returns the listener ID.
+func (t *Topic[T]) Subscribe(callback func(payload T), opts ...ListenerOption) string {
+	return t.emitter.OnErr(t.name, t.handler(callback), opts...)
+}
+
+// SubscribeOnce is Subscribe for a callback that is removed after it fires once.
+func (t *Topic[T]) SubscribeOnce(callback func(payload T), opts ...ListenerOption) string {
+	return t.emitter.OnceErr(t.name, t.handler(callback), opts...)
+}
+
+// Publish emits payload synchronously, like Emit.
+func (t *Topic[T]) Publish(payload T) EmitResult {
+	return t.emitter.Emit(t.name, payload)
+}
+
+// PublishAsync queues payload for the topic's listeners, like EmitAsync.
+func (t *Topic[T]) PublishAsync(payload T) int {
+	return t.emitter.EmitAsync(t.name, payload)
+}
+
+func (t *Topic[T]) handler(callback func(payload T)) func(data interface{}) error {
+	return func(data interface{}) error {
+		payload, ok := data.(T)
+		if !ok && data != nil {
+			return fmt.Errorf("emitter: topic %q got payload %T, want %T", t.name, data, payload)
+		}
+		callback(payload)
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package emitter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+type orderCreated struct {
+	ID    string
+	Total int
+}
+
+func TestTopicPublishAndSubscribe(t *testing.T) {
+	e := NewEventEmitter()
+	topic := NewTopic[orderCreated](e, "order.created")
+
+	received := []orderCreated{}
+	topic.Subscribe(func(order orderCreated) {
+		received = append(received, order)
+	})
+
+	result := topic.Publish(orderCreated{ID: "O-1", Total: 30})
+	if result.Delivered != 1 || result.Err() != nil {
+		t.Errorf("expected a clean delivery, got %+v", result)
+	}
+	if len(received) != 1 || received[0].ID != "O-1" || received[0].Total != 30 {
+		t.Errorf("subscriber should receive the typed payload, got %+v", received)
+	}
+
+	logs := e.GetEventLogsByName("order.created")
+	if len(logs) != 1 || logs[0].Data.(orderCreated).ID != "O-1" {
+		t.Error("published events should be logged like any other")
+	}
+}
+
+func TestTopicSharesEmitter(t *testing.T) {
+	e := NewEventEmitter()
+	topic := NewTopic[int](e, "score.updated")
+
+	sum := 0
+	id := topic.Subscribe(func(score int) { sum += score })
+
+	untyped := []interface{}{}
+	e.On("score.*", func(data interface{}) { untyped = append(untyped, data) })
+
+	topic.Publish(5)
+	e.Emit("score.updated", 7)
+	if sum != 12 || !slices.Equal(untyped, []interface{}{5, 7}) {
+		t.Errorf("typed and untyped sides should see each other's events, got %d and %v", sum, untyped)
+	}
+
+	if !e.Off(id) {
+		t.Error("topic subscriptions should be removable with Off")
+	}
+	topic.Publish(1)
+	if sum != 12 {
+		t.Error("removed subscription should not be called")
+	}
+}
+
+func TestTopicRejectsMistypedPayload(t *testing.T) {
+	e := NewEventEmitter()
+	topic := NewTopic[int](e, "score.updated")
+
+	calls := 0
+	topic.Subscribe(func(score int) { calls++ })
+
+	result := e.Emit("score.updated", "not a score")
+	if calls != 0 {
+		t.Error("callback should not get a payload of the wrong type")
+	}
+	if result.Err() == nil || !strings.Contains(result.Err().Error(), "got payload string, want int") {
+		t.Errorf("mistyped payload should be reported, got %v", result.Err())
+	}
+
+	// nil becomes the zero value
+	e.Emit("score.updated", nil)
+	if calls != 1 {
+		t.Error("nil payload should be delivered as the zero value")
+	}
+}
+
+func TestTopicSubscribeOnceAndAsync(t *testing.T) {
+	e := NewEventEmitter()
+	topic := NewTopic[string](e, "message")
+
+	received := make(chan string, 2)
+	topic.SubscribeOnce(func(message string) { received <- message }, WithFilter(func(data interface{}) bool {
+		return data != "skip"
+	}))
+
+	topic.PublishAsync("skip")
+	topic.PublishAsync("hello")
+	topic.PublishAsync("again")
+	e.Close()
+
+	if len(received) != 1 || <-received != "hello" {
+		t.Error("once subscriber should get only the first accepted payload")
+	}
+	if topic.Name() != "message" {
+		t.Errorf("unexpected topic name %q", topic.Name())
+	}
+}
@@ -0,0 +1,94 @@
+package emitter
+
+import "strings"
+
+// Event names are dot-separated segments such as "order.created". A pattern
+// may use "*" for exactly one segment and "**" for any number of segments,
+// including none: "order.*" matches "order.created" but not
+// "order.item.added", which "order.**" matches; "**" matches every event.
+const (
+	segmentWildcard = "*"
+	multiWildcard   = "**"
+)
+
+// topicTrie indexes the registered event names and patterns by segment, so
+// an emit finds every matching pattern without scanning all of them.
+type topicTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	terminal bool   // a registered name or pattern ends here
+	pattern  string // that name or pattern
+}
+
+func newTopicTrie() *topicTrie {
+	return &topicTrie{root: &trieNode{children: make(map[string]*trieNode)}}
+}
+
+func (t *topicTrie) insert(pattern string) {
+	node := t.root
+	for _, segment := range strings.Split(pattern, ".") {
+		child, exists := node.children[segment]
+		if !exists {
+			child = &trieNode{children: make(map[string]*trieNode)}
+			node.children[segment] = child
+		}
+		node = child
+	}
+	node.terminal = true
+	node.pattern = pattern
+}
+
+// remove unregisters pattern and prunes the nodes nothing else uses.
+func (t *topicTrie) remove(pattern string) {
+	removeSegments(t.root, strings.Split(pattern, "."))
+}
+
+// removeSegments reports whether node became empty and can be pruned.
+func removeSegments(node *trieNode, segments []string) bool {
+	if len(segments) == 0 {
+		node.terminal = false
+		node.pattern = ""
+	} else if child, exists := node.children[segments[0]]; exists {
+		if removeSegments(child, segments[1:]) {
+			delete(node.children, segments[0])
+		}
+	}
+	return !node.terminal && len(node.children) == 0
+}
+
+// match returns every registered name and pattern that matches eventName.
+func (t *topicTrie) match(eventName string) []string {
+	found := make(map[string]bool)
+	t.root.match(strings.Split(eventName, "."), found)
+
+	patterns := make([]string, 0, len(found))
+	for pattern := range found {
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+func (n *trieNode) match(segments []string, found map[string]bool) {
+	if len(segments) == 0 {
+		if n.terminal {
+			found[n.pattern] = true
+		}
+	} else {
+		if child, exists := n.children[segments[0]]; exists {
+			child.match(segments[1:], found)
+		}
+		if child, exists := n.children[segmentWildcard]; exists {
+			child.match(segments[1:], found)
+		}
+	}
+
+	// "**" may swallow any number of the remaining segments
+	if child, exists := n.children[multiWildcard]; exists {
+		for i := 0; i <= len(segments); i++ {
+			child.match(segments[i:], found)
+		}
+	}
+}
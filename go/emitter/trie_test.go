@@ -0,0 +1,156 @@
+package emitter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTopicTrieMatch(t *testing.T) {
+	trie := newTopicTrie()
+	for _, pattern := range []string{"order.created", "order.*", "order.**", "*", "**", "*.created", "order.**.added"} {
+		trie.insert(pattern)
+	}
+
+	tests := []struct {
+		eventName string
+		want      []string
+	}{
+		{"order.created", []string{"order.created", "order.*", "order.**", "**", "*.created"}},
+		{"order.shipped", []string{"order.*", "order.**", "**"}},
+		{"order.item.added", []string{"order.**", "**", "order.**.added"}},
+		{"order", []string{"order.**", "*", "**"}},
+		{"user.created", []string{"**", "*.created"}},
+		{"ping", []string{"*", "**"}},
+	}
+
+	for _, tt := range tests {
+		got := trie.match(tt.eventName)
+		slices.Sort(got)
+		slices.Sort(tt.want)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("match(%q) = %v, want %v", tt.eventName, got, tt.want)
+		}
+	}
+}
+
+func TestTopicTrieRemovePrunes(t *testing.T) {
+	trie := newTopicTrie()
+	trie.insert("order.*")
+	trie.insert("order.item.added")
+
+	trie.remove("order.item.added")
+	if got := trie.match("order.item.added"); len(got) != 0 {
+		t.Errorf("removed pattern should not match, got %v", got)
+	}
+	if _, exists := trie.root.children["order"].children["item"]; exists {
+		t.Error("unused nodes should be pruned")
+	}
+	if got := trie.match("order.created"); !slices.Equal(got, []string{"order.*"}) {
+		t.Errorf("other patterns should still match, got %v", got)
+	}
+
+	trie.remove("order.*")
+	if len(trie.root.children) != 0 {
+		t.Error("trie should be empty after removing every pattern")
+	}
+}
+
+func TestWildcardListeners(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := map[string]int{}
+	e.On("order.*", func(data interface{}) { calls["order.*"]++ })
+	e.On("order.**", func(data interface{}) { calls["order.**"]++ })
+	e.On("*", func(data interface{}) { calls["*"]++ })
+	e.On("order.created", func(data interface{}) { calls["order.created"]++ })
+
+	if count := e.Emit("order.created", nil).Delivered; count != 3 {
+		t.Errorf("order.created should reach 3 listeners, got %d", count)
+	}
+	if count := e.Emit("order.item.added", nil).Delivered; count != 1 {
+		t.Errorf("order.item.added should only reach order.**, got %d", count)
+	}
+	if count := e.Emit("ping", nil).Delivered; count != 1 {
+		t.Errorf("ping should only reach *, got %d", count)
+	}
+
+	want := map[string]int{"order.*": 1, "order.**": 2, "*": 1, "order.created": 1}
+	for pattern, n := range want {
+		if calls[pattern] != n {
+			t.Errorf("%s listener should be called %d times, got %d", pattern, n, calls[pattern])
+		}
+	}
+}
+
+func TestWildcardListenersCalledInRegistrationOrder(t *testing.T) {
+	e := NewEventEmitter()
+
+	order := []string{}
+	e.On("order.**", func(data interface{}) { order = append(order, "a") })
+	e.On("order.created", func(data interface{}) { order = append(order, "b") })
+	e.On("order.*", func(data interface{}) { order = append(order, "c") })
+	e.On("order.**", func(data interface{}) { order = append(order, "d") })
+
+	e.Emit("order.created", nil)
+	if !slices.Equal(order, []string{"a", "b", "c", "d"}) {
+		t.Errorf("listeners should be called in registration order, got %v", order)
+	}
+}
+
+func TestWildcardOnce(t *testing.T) {
+	e := NewEventEmitter()
+
+	calls := 0
+	e.Once("order.*", func(data interface{}) { calls++ })
+
+	e.Emit("order.created", nil)
+	e.Emit("order.shipped", nil)
+	if calls != 1 {
+		t.Errorf("once pattern listener should fire once, got %d", calls)
+	}
+	if e.ListenerCount("") != 0 || len(e.EventNames()) != 0 {
+		t.Error("once pattern listener should be removed")
+	}
+}
+
+func TestWildcardQueries(t *testing.T) {
+	e := NewEventEmitter()
+
+	id := e.On("order.*", func(data interface{}) {})
+	e.On("order.created", func(data interface{}) {})
+	e.On("user.created", func(data interface{}) {})
+
+	if e.ListenerCount("order.created") != 2 {
+		t.Errorf("order.created should count the pattern listener, got %d", e.ListenerCount("order.created"))
+	}
+	if e.ListenerCount("order.shipped") != 1 {
+		t.Errorf("order.shipped should be matched by order.*, got %d", e.ListenerCount("order.shipped"))
+	}
+	if e.ListenerCount("") != 3 {
+		t.Errorf("total should be 3, got %d", e.ListenerCount(""))
+	}
+
+	listeners := e.GetListeners("order.created")
+	if len(listeners) != 2 || listeners[0].ID != id || listeners[0].EventName != "order.*" {
+		t.Error("GetListeners should include the pattern listener with its pattern as EventName")
+	}
+
+	names := e.EventNames()
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"order.*", "order.created", "user.created"}) {
+		t.Errorf("EventNames should list names and patterns, got %v", names)
+	}
+
+	// OffAll on a name leaves matching patterns alone
+	if removed := e.OffAll("order.created"); removed != 1 {
+		t.Errorf("expected 1 removed, got %d", removed)
+	}
+	if e.ListenerCount("order.created") != 1 {
+		t.Error("order.* should still match order.created")
+	}
+
+	e.Off(id)
+	if e.ListenerCount("order.created") != 0 {
+		t.Error("order.* should be gone after Off")
+	}
+}
@@ -0,0 +1,52 @@
+package leaderboardv2
+
+import "time"
+
+// Events published through WithPublisher, with their payloads, by value.
+const (
+	EventPlayerAdded   = "player.added"   // PlayerAdded
+	EventPlayerRemoved = "player.removed" // PlayerRemoved
+	EventScoreUpdated  = "score.updated"  // ScoreUpdate, as recorded in the score history
+)
+
+// Publisher receives the leaderboard's domain events. *emitter.EventEmitter
+// from go/emitter satisfies it, so listeners get the events asynchronously
+// and in order.
+type Publisher interface {
+	EmitAsync(eventName string, data interface{}) int
+}
+
+// Option configures a Leaderboard.
+type Option func(*Leaderboard)
+
+// WithPublisher publishes an event when players join or leave and for
+// every score change.
+func WithPublisher(p Publisher) Option {
+	return func(lb *Leaderboard) {
+		lb.publisher = p
+	}
+}
+
+type PlayerAdded struct {
+	PlayerID string
+	Name     string
+	At       time.Time
+}
+
+type PlayerRemoved struct {
+	PlayerID string
+	At       time.Time
+}
+
+// publish sends an event if the leaderboard has a publisher.
+func (lb *Leaderboard) publish(eventName string, data interface{}) {
+	if lb.publisher != nil {
+		lb.publisher.EmitAsync(eventName, data)
+	}
+}
+
+// recordScore appends update to the score history and publishes it.
+func (lb *Leaderboard) recordScore(update ScoreUpdate) {
+	lb.scoreHistory = append(lb.scoreHistory, update)
+	lb.publish(EventScoreUpdated, update)
+}
@@ -0,0 +1,126 @@
+package leaderboardv2
+
+import (
+	"reflect"
+	"testing"
+
+	"emitter"
+)
+
+// announcement is one event the leaderboard published.
+type announcement struct {
+	event   string
+	payload interface{}
+}
+
+// scoreboard is a Publisher collecting the leaderboard's announcements in order.
+type scoreboard struct {
+	announcements []announcement
+}
+
+func (s *scoreboard) EmitAsync(eventName string, data interface{}) int {
+	s.announcements = append(s.announcements, announcement{eventName, data})
+	return 1
+}
+
+// publishedEvents runs fn against a leaderboard and returns what it announced.
+func publishedEvents(fn func(lb *Leaderboard)) []announcement {
+	board := &scoreboard{}
+	fn(NewLeaderboard(WithPublisher(board)))
+	return board.announcements
+}
+
+func TestPublishLeaderboardEvents(t *testing.T) {
+	announced := publishedEvents(func(lb *Leaderboard) {
+		lb.AddPlayer("p1", "Alice")
+		lb.AddPlayer("p1", "Duplicate") // rejected, no event
+		lb.AddScore("p1", 50)
+		lb.SetScore("p1", 20)
+		lb.AddScore("p2", 10) // unknown player, no event
+		lb.RemovePlayer("p1")
+	})
+
+	names := []string{}
+	for _, a := range announced {
+		names = append(names, a.event)
+	}
+	want := []string{EventPlayerAdded, EventScoreUpdated, EventScoreUpdated, EventPlayerRemoved}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+
+	if added := announced[0].payload.(PlayerAdded); added.PlayerID != "p1" || added.Name != "Alice" {
+		t.Errorf("unexpected added payload %+v", added)
+	}
+	first := announced[1].payload.(ScoreUpdate)
+	second := announced[2].payload.(ScoreUpdate)
+	if first.OldScore != 0 || first.NewScore != 50 || second.OldScore != 50 || second.NewScore != 20 {
+		t.Errorf("unexpected score updates %+v, %+v", first, second)
+	}
+	if removed := announced[3].payload.(PlayerRemoved); removed.PlayerID != "p1" {
+		t.Errorf("unexpected removed payload %+v", removed)
+	}
+}
+
+func TestPublishedScoresMatchHistory(t *testing.T) {
+	var history []ScoreUpdate
+	announced := publishedEvents(func(lb *Leaderboard) {
+		lb.AddPlayer("p1", "Alice")
+		lb.AddScore("p1", 5)
+		lb.AddScore("p1", -2)
+		history = lb.GetScoreHistory("p1")
+	})
+
+	published := []ScoreUpdate{}
+	for _, a := range announced {
+		if a.event == EventScoreUpdated {
+			published = append(published, a.payload.(ScoreUpdate))
+		}
+	}
+	if !reflect.DeepEqual(published, history) {
+		t.Errorf("expected published updates %+v to match history %+v", published, history)
+	}
+}
+
+func TestPublishScoreResets(t *testing.T) {
+	announced := publishedEvents(func(lb *Leaderboard) {
+		lb.AddPlayer("p1", "Alice")
+		lb.AddPlayer("p2", "Bob")
+		lb.SetScore("p1", 30)
+		lb.SetScore("p2", -10)
+		lb.ResetAllScores()
+	})
+
+	resets := map[string]ScoreUpdate{}
+	for _, a := range announced[4:] {
+		update := a.payload.(ScoreUpdate)
+		resets[update.PlayerID] = update
+	}
+	if len(resets) != 2 {
+		t.Fatalf("expected a reset per player, got %+v", announced[4:])
+	}
+	// Change is the size of the update, as for AddScore and SetScore
+	if got := resets["p1"]; got.OldScore != 30 || got.NewScore != 0 || got.Change != 30 {
+		t.Errorf("expected p1 reset from 30, got %+v", got)
+	}
+	if got := resets["p2"]; got.OldScore != -10 || got.NewScore != 0 || got.Change != 10 {
+		t.Errorf("expected p2 reset from -10, got %+v", got)
+	}
+}
+
+// An *emitter.EventEmitter is the Publisher the leaderboard is written for.
+func TestPublishToEmitter(t *testing.T) {
+	em := emitter.NewEventEmitter()
+	events, cancel := em.Subscribe("player.*")
+	defer cancel()
+
+	lb := NewLeaderboard(WithPublisher(em))
+	lb.AddPlayer("p1", "Alice")
+
+	// Close waits until the event reached the subscription
+	em.Close()
+	event := <-events
+	if added, ok := event.Data.(PlayerAdded); event.EventName != EventPlayerAdded || !ok || added.PlayerID != "p1" {
+		t.Errorf("unexpected event %s %+v", event.EventName, event.Data)
+	}
+}
@@ -29,13 +29,18 @@ type Leaderboard struct {
 	players      map[string]*Player
 	scoreHistory []ScoreUpdate
 	mu           sync.Mutex
+	publisher    Publisher
 }
 
-func NewLeaderboard() *Leaderboard {
-	return &Leaderboard{
+func NewLeaderboard(opts ...Option) *Leaderboard {
+	lb := &Leaderboard{
 		players:      make(map[string]*Player),
 		scoreHistory: make([]ScoreUpdate, 0),
 	}
+	for _, opt := range opts {
+		opt(lb)
+	}
+	return lb
 }
 
 // AddPlayer adds a new player with initial score of 0.
@@ -49,14 +54,16 @@ func (lb *Leaderboard) AddPlayer(id, name string) bool {
 		return false
 	}
 
-	lb.players[id] = &Player{
+	player := &Player{
 		ID:          id,
 		Name:        name,
 		Score:       0,
 		GamesPlayed: 0,
 		CreatedAt:   time.Now(),
 	}
+	lb.players[id] = player
 
+	lb.publish(EventPlayerAdded, PlayerAdded{PlayerID: id, Name: name, At: player.CreatedAt})
 	return true
 }
 
@@ -70,6 +77,7 @@ func (lb *Leaderboard) GetPlayer(id string) *Player {
 func (lb *Leaderboard) RemovePlayer(id string) bool {
 	if _, exists := lb.players[id]; exists {
 		delete(lb.players, id)
+		lb.publish(EventPlayerRemoved, PlayerRemoved{PlayerID: id, At: time.Now()})
 		return true
 	}
 	return false
@@ -91,7 +99,7 @@ func (lb *Leaderboard) AddScore(playerID string, points int) (int, bool) {
 
 		change := int(math.Abs(float64(oldScore - newScore)))
 
-		lb.scoreHistory = append(lb.scoreHistory, ScoreUpdate{
+		lb.recordScore(ScoreUpdate{
 			PlayerID:  playerID,
 			OldScore:  oldScore,
 			NewScore:  newScore,
@@ -118,7 +126,7 @@ func (lb *Leaderboard) SetScore(playerID string, score int) bool {
 
 		change := int(math.Abs(float64(oldScore - newScore)))
 
-		lb.scoreHistory = append(lb.scoreHistory, ScoreUpdate{
+		lb.recordScore(ScoreUpdate{
 			PlayerID:  playerID,
 			OldScore:  oldScore,
 			NewScore:  newScore,
@@ -300,18 +308,16 @@ func (lb *Leaderboard) GetAverageScore() float64 {
 func (lb *Leaderboard) ResetAllScores() int {
 	reset := len(lb.players)
 
-	for _, p := range lb.players {
-		p.Score = 0
-	}
-
 	lb.scoreHistory = []ScoreUpdate{}
 
 	for _, p := range lb.players {
-		lb.scoreHistory = append(lb.scoreHistory, ScoreUpdate{
+		oldScore := p.Score
+		p.Score = 0
+		lb.recordScore(ScoreUpdate{
 			PlayerID:  p.ID,
-			OldScore:  p.Score,
+			OldScore:  oldScore,
 			NewScore:  0,
-			Change:    p.Score,
+			Change:    int(math.Abs(float64(oldScore))),
 			Timestamp: time.Now(),
 		})
 	}
@@ -0,0 +1,58 @@
+package order
+
+import "time"
+
+// Events published through WithPublisher. Each carries the payload struct
+// of the same name, by value.
+const (
+	EventOrderCreated       = "order.created"        // OrderCreated
+	EventOrderStatusChanged = "order.status_changed" // OrderStatusChanged
+)
+
+// Publisher receives the order domain events. *emitter.EventEmitter from
+// go/emitter satisfies it, so listeners get the events asynchronously and
+// in order.
+type Publisher interface {
+	EmitAsync(eventName string, data interface{}) int
+}
+
+// Option configures an OrderManager.
+type Option func(*OrderManager)
+
+// WithPublisher publishes an event when an order is created and on every
+// status change, including automatic cancellations.
+func WithPublisher(p Publisher) Option {
+	return func(om *OrderManager) {
+		om.publisher = p
+	}
+}
+
+type OrderCreated struct {
+	OrderID string
+	Items   []OrderItem
+	Total   int
+	At      time.Time
+}
+
+type OrderStatusChanged struct {
+	OrderID string
+	From    OrderStatus
+	To      OrderStatus
+	At      time.Time
+}
+
+// publish sends an event if the manager has a publisher.
+func (om *OrderManager) publish(eventName string, data interface{}) {
+	if om.publisher != nil {
+		om.publisher.EmitAsync(eventName, data)
+	}
+}
+
+func (om *OrderManager) publishStatusChange(id string, from, to OrderStatus) {
+	om.publish(EventOrderStatusChanged, OrderStatusChanged{
+		OrderID: id,
+		From:    from,
+		To:      to,
+		At:      time.Now(),
+	})
+}
@@ -0,0 +1,93 @@
+package order
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"emitter"
+)
+
+// orderEvent is one event the manager published.
+type orderEvent struct {
+	name   string
+	change interface{}
+}
+
+// orderFeed is a Publisher collecting the manager's events in order.
+type orderFeed []orderEvent
+
+func (f *orderFeed) EmitAsync(eventName string, data interface{}) int {
+	*f = append(*f, orderEvent{eventName, data})
+	return 1
+}
+
+// publishedEvents runs fn against a manager and returns what it published.
+func publishedEvents(fn func(om *OrderManager)) orderFeed {
+	feed := orderFeed{}
+	fn(NewOrderManager(WithPublisher(&feed)))
+	return feed
+}
+
+func TestPublishOrderLifecycle(t *testing.T) {
+	events := publishedEvents(func(om *OrderManager) {
+		id := om.CreateOrder([]OrderItem{{Name: "Burger", Price: 100, Quantity: 2}})
+		om.UpdateStatus(id, StatusConfirmed)
+		om.UpdateStatus(id, StatusDelivered) // invalid, no event
+		om.UpdateStatus(id, StatusPreparing)
+		om.UpdateStatus(id, StatusReady)
+		om.UpdateStatus(id, StatusDelivered)
+	})
+
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d", len(events))
+	}
+	created := events[0].change.(OrderCreated)
+	if events[0].name != EventOrderCreated || created.OrderID != "ORD-1" || created.Total != 200 {
+		t.Errorf("unexpected created event %s %+v", events[0].name, created)
+	}
+
+	want := []OrderStatus{StatusPending, StatusConfirmed, StatusPreparing, StatusReady, StatusDelivered}
+	for i, event := range events[1:] {
+		changed := event.change.(OrderStatusChanged)
+		if event.name != EventOrderStatusChanged || changed.From != want[i] || changed.To != want[i+1] {
+			t.Errorf("event %d: expected %s -> %s, got %s %+v", i+1, want[i], want[i+1], event.name, changed)
+		}
+	}
+}
+
+func TestPublishOldPendingCancellation(t *testing.T) {
+	events := publishedEvents(func(om *OrderManager) {
+		id := om.CreateOrder([]OrderItem{{Name: "Pizza", Price: 200, Quantity: 1}})
+		om.GetOrder(id).CreatedAt = time.Now().Add(-time.Hour)
+		om.CancelOldPendingOrders(time.Minute)
+	})
+
+	names := []string{}
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if !reflect.DeepEqual(names, []string{EventOrderCreated, EventOrderStatusChanged}) {
+		t.Fatalf("unexpected events %v", names)
+	}
+	if changed := events[1].change.(OrderStatusChanged); changed.To != StatusCancelled {
+		t.Errorf("expected a cancellation, got %+v", changed)
+	}
+}
+
+// An *emitter.EventEmitter is the Publisher the manager is written for.
+func TestPublishToEmitter(t *testing.T) {
+	em := emitter.NewEventEmitter()
+	events, cancel := em.Subscribe("order.*")
+	defer cancel()
+
+	om := NewOrderManager(WithPublisher(em))
+	id := om.CreateOrder([]OrderItem{{Name: "Burger", Price: 100, Quantity: 1}})
+
+	// Close waits until the event reached the subscription
+	em.Close()
+	event := <-events
+	if created, ok := event.Data.(OrderCreated); event.EventName != EventOrderCreated || !ok || created.OrderID != id {
+		t.Errorf("unexpected event %s %+v", event.EventName, event.Data)
+	}
+}
@@ -0,0 +1,7 @@
+module order
+
+go 1.23
+
+require emitter v0.0.0
+
+replace emitter => ../emitter
@@ -2,6 +2,7 @@ package order
 
 import (
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -32,6 +33,7 @@ type Order struct {
 type OrderManager struct {
 	orders       map[string]*Order
 	LastSequence int
+	publisher    Publisher
 }
 
 var validTransitions = map[OrderStatus]map[OrderStatus]bool{
@@ -43,10 +45,14 @@ var validTransitions = map[OrderStatus]map[OrderStatus]bool{
 	StatusCancelled: {},
 }
 
-func NewOrderManager() *OrderManager {
-	return &OrderManager{
+func NewOrderManager(opts ...Option) *OrderManager {
+	om := &OrderManager{
 		orders: map[string]*Order{},
 	}
+	for _, opt := range opts {
+		opt(om)
+	}
+	return om
 }
 
 // CreateOrder creates a new order with pending status.
@@ -57,12 +63,20 @@ func (om *OrderManager) CreateOrder(items []OrderItem) string {
 	newSequence := om.LastSequence + 1
 	om.LastSequence = newSequence
 	newId := fmt.Sprintf("%s-%d", "ORD", newSequence)
-	om.orders[newId] = &Order{
+	order := &Order{
 		ID:        newId,
 		Items:     items,
 		Status:    StatusPending,
 		CreatedAt: time.Now(),
 	}
+	om.orders[newId] = order
+
+	om.publish(EventOrderCreated, OrderCreated{
+		OrderID: newId,
+		Items:   slices.Clone(items),
+		Total:   om.GetOrderTotal(newId),
+		At:      order.CreatedAt,
+	})
 	return newId
 }
 
@@ -92,7 +106,9 @@ func (om *OrderManager) UpdateStatus(id string, newStatus OrderStatus) bool {
 	}
 
 	if validTransitions[order.Status][newStatus] {
+		oldStatus := order.Status
 		order.Status = newStatus
+		om.publishStatusChange(id, oldStatus, newStatus)
 		return true
 	}
 
@@ -135,6 +151,7 @@ func (om *OrderManager) CancelOldPendingOrders(maxAge time.Duration) int {
 		if order.Status == StatusPending && time.Since(order.CreatedAt) > maxAge {
 			order.Status = StatusCancelled
 			cancelledCount++
+			om.publishStatusChange(order.ID, StatusPending, StatusCancelled)
 		}
 	}
 	return cancelledCount
@@ -0,0 +1,14 @@
+package taskqueue
+
+import "time"
+
+// Clock tells the queue what time it is, for timestamps, retry backoff and
+// leases.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the wall clock; queues use it unless WithClock says otherwise.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
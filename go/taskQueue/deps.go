@@ -0,0 +1,172 @@
+package taskqueue
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SubmitTaskWithDeps is SubmitTask for a task that may only run once every
+// task in dependsOn has completed. Until then it is pending but AssignTask
+// skips it. If a dependency fails with no retries left, the task fails too,
+// and so do the tasks depending on it; they are not put on the dead-letter
+// list.
+// Dependencies must already have been submitted, so they can never form a
+// cycle: a task cannot depend on itself or on one submitted after it.
+// Returns empty string and false if a dependency is unknown or maxRetries < 0.
+func (tq *TaskQueue) SubmitTaskWithDeps(name string, priority Priority, maxRetries int, dependsOn []string, opts ...TaskOption) (string, bool) {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	parents := make([]*Task, 0, len(dependsOn))
+	for _, id := range dependsOn {
+		parent, ok := tq.tasks[id]
+		if !ok {
+			return "", false
+		}
+		if !slices.Contains(parents, parent) {
+			parents = append(parents, parent)
+		}
+	}
+	return tq.submit(name, priority, maxRetries, nil, parents, opts)
+}
+
+// addDependencies links a new task to its dependencies. Returns one that
+// has already failed, if any. Callers must hold tq.mu.
+func (tq *TaskQueue) addDependencies(task *Task, parents []*Task) *Task {
+	var failed *Task
+	for _, parent := range parents {
+		task.DependsOn = append(task.DependsOn, parent.ID)
+		parent.children = append(parent.children, task)
+		switch parent.Status {
+		case StatusCompleted:
+			continue
+		case StatusFailed:
+			failed = parent
+		}
+		task.waitingOn++
+	}
+	return failed
+}
+
+// releaseDependents lets the dependents of a completed task run once it was
+// the last dependency they waited on. Callers must hold tq.mu.
+func (tq *TaskQueue) releaseDependents(task *Task) {
+	for _, child := range task.children {
+		child.waitingOn--
+		if child.waitingOn == 0 && child.Status == StatusPending {
+			delete(tq.blocked, child.ID)
+			tq.enqueue(child)
+		}
+	}
+}
+
+// failDependents fails every task still waiting on a task that failed for
+// good, and their dependents in turn. Callers must hold tq.mu.
+func (tq *TaskQueue) failDependents(task *Task) {
+	for _, child := range task.children {
+		if child.Status == StatusPending {
+			tq.failDependent(child, task)
+		}
+	}
+}
+
+func (tq *TaskQueue) failDependent(task, cause *Task) {
+	delete(tq.blocked, task.ID)
+	task.Status = StatusFailed
+	reason := fmt.Sprintf("dependency %s failed", cause.ID)
+	tq.publishFailure(EventTaskFailed, task, "", reason, tq.clock.Now())
+	tq.failDependents(task)
+}
+
+// reviveDependents undoes failDependents for a task taken off the
+// dead-letter list: its dependents wait on it again, unless another of their
+// dependencies has failed. Callers must hold tq.mu.
+func (tq *TaskQueue) reviveDependents(task *Task) {
+	for _, child := range task.children {
+		// It never ran, as task never completed: failed by failDependents
+		if child.Status != StatusFailed || tq.hasFailedDependency(child) {
+			continue
+		}
+		tq.enqueue(child)
+		tq.publish(EventTaskRequeued, TaskRequeued{TaskID: child.ID, At: tq.clock.Now()})
+		tq.reviveDependents(child)
+	}
+}
+
+func (tq *TaskQueue) hasFailedDependency(task *Task) bool {
+	for _, id := range task.DependsOn {
+		if tq.tasks[id].Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowState summarises a workflow: a set of tasks linked by
+// dependencies, directly or not.
+type WorkflowState struct {
+	// Status is StatusFailed if any task failed for good, StatusCompleted
+	// once every task has, StatusRunning once any task has started, and
+	// StatusPending before that.
+	Status    TaskStatus
+	TaskIDs   []string // in submission order
+	Pending   int
+	Running   int
+	Completed int
+	Failed    int
+}
+
+// GetWorkflowState returns the state of the workflow the task belongs to.
+// A task without dependencies or dependents is a workflow of its own.
+// Returns false if the task doesn't exist.
+func (tq *TaskQueue) GetWorkflowState(taskID string) (WorkflowState, bool) {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	start, ok := tq.tasks[taskID]
+	if !ok {
+		return WorkflowState{}, false
+	}
+
+	seen := map[*Task]bool{start: true}
+	tasks := []*Task{start}
+	for i := 0; i < len(tasks); i++ {
+		linked := slices.Clone(tasks[i].children)
+		for _, id := range tasks[i].DependsOn {
+			linked = append(linked, tq.tasks[id])
+		}
+		for _, t := range linked {
+			if !seen[t] {
+				seen[t] = true
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	slices.SortFunc(tasks, func(a, b *Task) int { return a.seq - b.seq })
+
+	var state WorkflowState
+	for _, t := range tasks {
+		state.TaskIDs = append(state.TaskIDs, t.ID)
+		switch t.Status {
+		case StatusPending:
+			state.Pending++
+		case StatusRunning:
+			state.Running++
+		case StatusCompleted:
+			state.Completed++
+		case StatusFailed:
+			state.Failed++
+		}
+	}
+	switch {
+	case state.Failed > 0:
+		state.Status = StatusFailed
+	case state.Completed == len(tasks):
+		state.Status = StatusCompleted
+	case state.Running > 0 || state.Completed > 0:
+		state.Status = StatusRunning
+	default:
+		state.Status = StatusPending
+	}
+	return state, true
+}
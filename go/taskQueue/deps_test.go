@@ -0,0 +1,200 @@
+package taskqueue
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestDependentWaitsForParents(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 5)
+	fetch, _ := tq.SubmitTask("Fetch", PriorityLow, 0)
+	parse, _ := tq.SubmitTask("Parse", PriorityLow, 0)
+	report, ok := tq.SubmitTaskWithDeps("Report", PriorityHigh, 0, []string{fetch, parse, fetch})
+	if !ok {
+		t.Fatal("expected SubmitTaskWithDeps to succeed")
+	}
+	if deps := tq.GetTask(report).DependsOn; !slices.Equal(deps, []string{fetch, parse}) {
+		t.Errorf("expected duplicate dependencies dropped, got %v", deps)
+	}
+	if len(tq.GetPendingTasks()) != 3 {
+		t.Error("expected the waiting task to be listed as pending")
+	}
+
+	// Despite its priority, Report waits for both
+	for _, want := range []string{fetch, parse} {
+		if taskID, _, _ := tq.AssignTask(); taskID != want {
+			t.Errorf("expected %s, got %s", want, taskID)
+		}
+	}
+	if _, _, ok := tq.AssignTask(); ok {
+		t.Error("expected no assignment while dependencies run")
+	}
+
+	tq.CompleteTask(fetch, "w1")
+	if _, _, ok := tq.AssignTask(); ok {
+		t.Error("expected the task to wait for its last dependency")
+	}
+	tq.CompleteTask(parse, "w1")
+	if taskID, _, ok := tq.AssignTask(); !ok || taskID != report {
+		t.Errorf("expected %s once its dependencies completed, got %s", report, taskID)
+	}
+}
+
+func TestSubmitTaskWithDepsValidation(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	done, _ := tq.SubmitTask("Done", PriorityLow, 0)
+	taskID, workerID, _ := tq.AssignTask()
+	tq.CompleteTask(taskID, workerID)
+
+	// Its own ID, TASK-2, is not known yet: no cycle can be formed
+	if _, ok := tq.SubmitTaskWithDeps("Self", PriorityLow, 0, []string{"TASK-2"}); ok {
+		t.Error("expected a dependency on the task itself to be rejected")
+	}
+	if _, ok := tq.SubmitTaskWithDeps("Orphan", PriorityLow, 0, []string{"TASK-999"}); ok {
+		t.Error("expected an unknown dependency to be rejected")
+	}
+	if _, ok := tq.SubmitTaskWithDeps("Bad", PriorityLow, -2, []string{done}); ok {
+		t.Error("expected invalid maxRetries to be rejected")
+	}
+
+	id, ok := tq.SubmitTaskWithDeps("After", PriorityLow, 0, []string{done})
+	if !ok || id != "TASK-2" {
+		t.Fatalf("expected TASK-2, got %s", id)
+	}
+	if taskID, _, ok := tq.AssignTask(); !ok || taskID != id {
+		t.Error("expected a task whose dependencies already completed to be assigned")
+	}
+}
+
+func TestFailedParentFailsDescendants(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	root, _ := tq.SubmitTask("Root", PriorityMedium, 1)
+	other, _ := tq.SubmitTask("Other", PriorityLow, 0)
+	child, _ := tq.SubmitTaskWithDeps("Child", PriorityMedium, 0, []string{root})
+	grandchild, _ := tq.SubmitTaskWithDeps("Grandchild", PriorityMedium, 0, []string{child, other})
+
+	// A retry is not a permanent failure
+	taskID, workerID, _ := tq.AssignTask()
+	tq.FailTask(taskID, workerID)
+	if tq.GetTask(child).Status != StatusPending {
+		t.Fatal("expected dependents to keep waiting while the parent retries")
+	}
+
+	taskID, workerID, _ = tq.AssignTask()
+	tq.FailTaskWithReason(taskID, workerID, "gave up")
+	for _, id := range []string{child, grandchild} {
+		if status := tq.GetTask(id).Status; status != StatusFailed {
+			t.Errorf("expected %s to fail with its dependency, got %s", id, status)
+		}
+	}
+	if dead := tq.GetDeadLetters(); len(dead) != 1 || dead[0].ID != root {
+		t.Errorf("expected only the root on the dead-letter list, got %v", dead)
+	}
+	if taskID, _, _ := tq.AssignTask(); taskID != other {
+		t.Errorf("expected unrelated tasks to run, got %s", taskID)
+	}
+
+	late, _ := tq.SubmitTaskWithDeps("Late", PriorityMedium, 0, []string{root})
+	if tq.GetTask(late).Status != StatusFailed {
+		t.Error("expected a task submitted after its dependency failed to fail at once")
+	}
+
+	// Requeueing the root revives what failed because of it
+	tq.CompleteTask(other, "w1")
+	tq.RequeueDeadLetter(root)
+	for _, id := range []string{child, grandchild, late} {
+		if status := tq.GetTask(id).Status; status != StatusPending {
+			t.Errorf("expected %s to wait again, got %s", id, status)
+		}
+	}
+	for _, want := range []string{root, child, grandchild, late} {
+		taskID, workerID, _ := tq.AssignTask()
+		if taskID != want {
+			t.Errorf("expected %s, got %s", want, taskID)
+		}
+		tq.CompleteTask(taskID, workerID)
+	}
+}
+
+func TestGetWorkflowState(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	a, _ := tq.SubmitTask("A", PriorityMedium, 0)
+	b, _ := tq.SubmitTask("B", PriorityMedium, 0)
+	c, _ := tq.SubmitTaskWithDeps("C", PriorityMedium, 0, []string{a})
+	d, _ := tq.SubmitTaskWithDeps("D", PriorityMedium, 0, []string{b})
+	tq.SubmitTask("Unrelated", PriorityLow, 0)
+
+	state, ok := tq.GetWorkflowState(c)
+	if !ok || state.Status != StatusPending || !slices.Equal(state.TaskIDs, []string{a, c}) || state.Pending != 2 {
+		t.Errorf("expected A and C pending, got %+v", state)
+	}
+	if _, ok := tq.GetWorkflowState("TASK-999"); ok {
+		t.Error("expected an unknown task to have no workflow")
+	}
+
+	// E joins both chains into one workflow
+	e, _ := tq.SubmitTaskWithDeps("E", PriorityMedium, 0, []string{c, d})
+	state, _ = tq.GetWorkflowState(a)
+	if want := []string{a, b, c, d, e}; !slices.Equal(state.TaskIDs, want) {
+		t.Errorf("expected %v, got %v", want, state.TaskIDs)
+	}
+
+	tq.AssignTask()
+	if state, _ := tq.GetWorkflowState(e); state.Status != StatusRunning || state.Running != 1 {
+		t.Errorf("expected running, got %+v", state)
+	}
+
+	for i := 0; i < 4; i++ {
+		tq.CompleteTask(tq.GetWorkerTasks("w1")[0].ID, "w1")
+		tq.AssignTask()
+	}
+	state, _ = tq.GetWorkflowState(e)
+	if state.Status != StatusRunning || state.Completed != 4 || state.Running != 1 {
+		t.Errorf("expected E to run last, got %+v", state)
+	}
+	tq.FailTask(e, "w1")
+	if state, _ := tq.GetWorkflowState(e); state.Status != StatusFailed || state.Failed != 1 {
+		t.Errorf("expected failed, got %+v", state)
+	}
+
+	tq.SubmitTaskWithDeps("F", PriorityMedium, 0, nil)
+	if state, _ := tq.GetWorkflowState("TASK-7"); !slices.Equal(state.TaskIDs, []string{"TASK-7"}) {
+		t.Errorf("expected a task on its own, got %v", state.TaskIDs)
+	}
+}
+
+func TestRuntimeRunsWorkflow(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 3)
+
+	var mu sync.Mutex
+	var order []string
+	step := func(name string) TaskOption {
+		return WithHandler(func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		})
+	}
+	extract, _ := tq.SubmitTaskWithDeps("extract", PriorityLow, 0, nil, step("extract"))
+	transform, _ := tq.SubmitTaskWithDeps("transform", PriorityLow, 0, []string{extract}, step("transform"))
+	tq.SubmitTaskWithDeps("load", PriorityHigh, 0, []string{transform}, step("load"))
+
+	tq.Start()
+	waitFor(t, func() bool {
+		state, _ := tq.GetWorkflowState(extract)
+		return state.Status == StatusCompleted
+	})
+	shutdown(t, tq)
+
+	if want := []string{"extract", "transform", "load"}; !slices.Equal(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
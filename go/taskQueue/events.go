@@ -0,0 +1,90 @@
+package taskqueue
+
+import "time"
+
+// Events published through WithPublisher. Each carries the payload struct
+// of the same name, by value.
+const (
+	EventTaskSubmitted = "task.submitted" // TaskSubmitted
+	EventTaskAssigned  = "task.assigned"  // TaskAssigned
+	EventTaskCompleted = "task.completed" // TaskCompleted
+	EventTaskRetrying  = "task.retrying"  // TaskFailed, the task is pending again
+	EventTaskFailed    = "task.failed"    // TaskFailed, no retries left
+	EventTaskAbandoned = "task.abandoned" // TaskAbandoned, the task is pending again
+	EventTaskRequeued  = "task.requeued"  // TaskRequeued, taken off the dead-letter list
+)
+
+// Publisher receives the queue's domain events. *emitter.EventEmitter from
+// go/emitter satisfies it, so listeners get the events asynchronously.
+// Events are published after the change they describe, outside the queue's
+// lock; those from one goroutine arrive in order.
+type Publisher interface {
+	EmitAsync(eventName string, data interface{}) int
+}
+
+type TaskSubmitted struct {
+	TaskID   string
+	Name     string
+	Priority Priority
+	At       time.Time
+}
+
+type TaskAssigned struct {
+	TaskID         string
+	WorkerID       string
+	FencingToken   uint64
+	LeaseExpiresAt time.Time
+	At             time.Time
+}
+
+type TaskCompleted struct {
+	TaskID   string
+	WorkerID string
+	At       time.Time
+}
+
+type TaskFailed struct {
+	TaskID        string
+	WorkerID      string
+	Reason        string
+	RetryCount    int // retries used, including the one about to start
+	MaxRetries    int
+	NextAttemptAt time.Time // when the retry may start; zero if at once or not retrying
+	At            time.Time
+}
+
+type TaskRequeued struct {
+	TaskID string
+	At     time.Time
+}
+
+type TaskAbandoned struct {
+	TaskID   string
+	WorkerID string // the worker whose lease expired, that timed out or was shut down
+	At       time.Time
+}
+
+type outgoingEvent struct {
+	name string
+	data interface{}
+}
+
+// publish queues an event for the publisher, if there is one.
+// Callers must hold tq.mu.
+func (tq *TaskQueue) publish(eventName string, data interface{}) {
+	if tq.publisher != nil {
+		tq.outgoing = append(tq.outgoing, outgoingEvent{eventName, data})
+	}
+}
+
+// unlock releases tq.mu and then publishes the events queued while it was
+// held, so a slow publisher never holds up the queue.
+func (tq *TaskQueue) unlock() {
+	events := tq.outgoing
+	tq.outgoing = nil
+	tq.mu.Unlock()
+
+	for _, event := range events {
+		tq.publisher.EmitAsync(event.name, event.data)
+	}
+}
@@ -0,0 +1,138 @@
+package taskqueue
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"emitter"
+)
+
+// taskEvent is one event the queue published.
+type taskEvent struct {
+	name    string
+	payload interface{}
+}
+
+// eventLog is a Publisher keeping the queue's events in publishing order.
+// The runtime publishes from its own goroutines, hence the lock.
+type eventLog struct {
+	mu     sync.Mutex
+	events []taskEvent
+}
+
+func (l *eventLog) EmitAsync(eventName string, data interface{}) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, taskEvent{eventName, data})
+	return 1
+}
+
+func (l *eventLog) all() []taskEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.events)
+}
+
+// publishedEvents runs fn against a queue and returns what it published.
+func publishedEvents(fn func(tq *TaskQueue)) []taskEvent {
+	log := &eventLog{}
+	fn(NewTaskQueue(WithPublisher(log)))
+	return log.all()
+}
+
+func eventNames(events []taskEvent) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.name
+	}
+	return names
+}
+
+func TestPublishTaskLifecycle(t *testing.T) {
+	events := publishedEvents(func(tq *TaskQueue) {
+		tq.AddWorker("w1", "Worker One", 1)
+		id, _ := tq.SubmitTask("email", PriorityHigh, 1)
+		tq.AssignTask()
+		tq.FailTask(id, "w1")
+		tq.AssignTask()
+		tq.FailTask(id, "w1")
+
+		id, _ = tq.SubmitTask("report", PriorityLow, 0)
+		tq.AssignTask()
+		tq.CompleteTask(id, "w1")
+	})
+
+	want := []string{
+		EventTaskSubmitted, EventTaskAssigned, EventTaskRetrying, EventTaskAssigned, EventTaskFailed,
+		EventTaskSubmitted, EventTaskAssigned, EventTaskCompleted,
+	}
+	if got := eventNames(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	submitted := events[0].payload.(TaskSubmitted)
+	if submitted.TaskID != "TASK-1" || submitted.Name != "email" || submitted.Priority != PriorityHigh {
+		t.Errorf("unexpected submitted payload %+v", submitted)
+	}
+	if assigned := events[1].payload.(TaskAssigned); assigned.TaskID != "TASK-1" || assigned.WorkerID != "w1" {
+		t.Errorf("unexpected assigned payload %+v", assigned)
+	}
+	if retrying := events[2].payload.(TaskFailed); retrying.RetryCount != 1 || retrying.MaxRetries != 1 {
+		t.Errorf("unexpected retrying payload %+v", retrying)
+	}
+	if failed := events[4].payload.(TaskFailed); failed.WorkerID != "w1" || failed.RetryCount != 1 {
+		t.Errorf("unexpected failed payload %+v", failed)
+	}
+	if completed := events[7].payload.(TaskCompleted); completed.TaskID != "TASK-2" || completed.At.IsZero() {
+		t.Errorf("unexpected completed payload %+v", completed)
+	}
+}
+
+func TestPublishAbandonedTask(t *testing.T) {
+	events := publishedEvents(func(tq *TaskQueue) {
+		tq.AddWorker("w1", "Worker One", 1)
+		tq.SubmitTask("email", PriorityMedium, -1)
+		tq.AssignTask()
+		tq.GetTask("TASK-1").StartedAt = time.Now().Add(-time.Hour)
+		tq.ReassignAbandonedTasks(time.Minute)
+	})
+
+	want := []string{EventTaskSubmitted, EventTaskAssigned, EventTaskAbandoned}
+	if got := eventNames(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if abandoned := events[2].payload.(TaskAbandoned); abandoned.WorkerID != "w1" {
+		t.Errorf("expected the timed out worker in the payload, got %+v", abandoned)
+	}
+}
+
+func TestNoEventsForRejectedCalls(t *testing.T) {
+	events := publishedEvents(func(tq *TaskQueue) {
+		tq.SubmitTask("bad", PriorityLow, -5)
+		tq.AssignTask()
+		tq.CompleteTask("TASK-1", "w1")
+	})
+	if len(events) != 0 {
+		t.Errorf("expected no events, got %v", eventNames(events))
+	}
+}
+
+// An *emitter.EventEmitter is the Publisher the queue is written for.
+func TestPublishToEmitter(t *testing.T) {
+	em := emitter.NewEventEmitter()
+	events, cancel := em.Subscribe("task.*")
+	defer cancel()
+
+	tq := NewTaskQueue(WithPublisher(em))
+	id, _ := tq.SubmitTask("email", PriorityHigh, 0)
+
+	// Close waits until the event reached the subscription
+	em.Close()
+	event := <-events
+	if submitted, ok := event.Data.(TaskSubmitted); event.EventName != EventTaskSubmitted || !ok || submitted.TaskID != id {
+		t.Errorf("unexpected event %s %+v", event.EventName, event.Data)
+	}
+}
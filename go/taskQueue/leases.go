@@ -0,0 +1,191 @@
+package taskqueue
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// DefaultLeaseDuration is how long an assignment lasts without a Heartbeat,
+// unless WithLeaseDuration says otherwise.
+const DefaultLeaseDuration = 30 * time.Second
+
+// minHeartbeatInterval bounds how often the runtime heartbeats, for leases
+// too short to divide into a ticker interval.
+const minHeartbeatInterval = time.Millisecond
+
+// leaseHeap holds the running tasks, the lease expiring first on top.
+type leaseHeap []*Task
+
+func (h leaseHeap) Len() int { return len(h) }
+
+func (h leaseHeap) Less(i, j int) bool {
+	return h[i].LeaseExpiresAt.Before(h[j].LeaseExpiresAt)
+}
+
+func (h leaseHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *leaseHeap) Push(x any) {
+	task := x.(*Task)
+	task.heapIndex = len(*h)
+	*h = append(*h, task)
+}
+
+func (h *leaseHeap) Pop() any {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.heapIndex = -1
+	*h = old[:n-1]
+	return task
+}
+
+// unassign takes a running task off its worker and drops its lease.
+// Callers must hold tq.mu.
+func (tq *TaskQueue) unassign(task *Task, worker *Worker) {
+	heap.Remove(&tq.leases, task.heapIndex)
+	task.LeaseExpiresAt = time.Time{}
+	tq.setTaskCount(worker, worker.TaskCount-1)
+}
+
+// Heartbeat extends the lease of a running task to the lease duration from
+// now. A worker keeps a task until it is reclaimed, so a heartbeat after the
+// lease expired still extends it if no reaper got there first.
+// Returns false if task doesn't exist, not running, or workerID doesn't match:
+// the worker no longer owns the task and should stop working on it.
+// A worker that may have lost its lease should use HeartbeatWithToken.
+func (tq *TaskQueue) Heartbeat(taskID, workerID string) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	task, _, ok := tq.runningTask(taskID, workerID)
+	if !ok {
+		return false
+	}
+	tq.extendLease(task)
+	return true
+}
+
+// HeartbeatWithToken is Heartbeat for the assignment with the given
+// FencingToken only.
+func (tq *TaskQueue) HeartbeatWithToken(taskID, workerID string, token uint64) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	task, _, ok := tq.fencedTask(taskID, workerID, token)
+	if !ok {
+		return false
+	}
+	tq.extendLease(task)
+	return true
+}
+
+func (tq *TaskQueue) extendLease(task *Task) {
+	task.LeaseExpiresAt = tq.clock.Now().Add(tq.leaseDuration)
+	heap.Fix(&tq.leases, task.heapIndex)
+}
+
+// ReclaimExpiredLeases resets running tasks whose lease has expired to
+// pending, without using a retry, and decrements their workers' TaskCount.
+// Tasks that are heartbeated are never reclaimed, however long they run.
+// A previous owner still working on a reclaimed task is refused by the calls
+// taking a FencingToken, even once the task is assigned to it again.
+// Returns the count of tasks reclaimed.
+func (tq *TaskQueue) ReclaimExpiredLeases() int {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	return tq.reclaimExpired(tq.clock.Now())
+}
+
+// reclaimExpired requeues the tasks whose lease expired by now.
+// Callers must hold tq.mu.
+func (tq *TaskQueue) reclaimExpired(now time.Time) int {
+	count := 0
+	for len(tq.leases) > 0 && !tq.leases[0].LeaseExpiresAt.After(now) {
+		task := tq.leases[0]
+		tq.requeue(task, tq.workers[task.WorkerID])
+		count++
+	}
+	return count
+}
+
+// nextTimeout returns when the next delayed task may be assigned or the
+// next lease expires, whichever is first. Callers must hold tq.mu.
+func (tq *TaskQueue) nextTimeout() (time.Time, bool) {
+	next, ok := tq.nextDue()
+	if len(tq.leases) > 0 && (!ok || tq.leases[0].LeaseExpiresAt.Before(next)) {
+		return tq.leases[0].LeaseExpiresAt, true
+	}
+	return next, ok
+}
+
+// CompleteTaskWithToken is CompleteTask for the assignment with the given
+// FencingToken only. A worker whose lease expired and whose task was
+// reassigned, even to the same worker, is rejected.
+func (tq *TaskQueue) CompleteTaskWithToken(taskID, workerID string, token uint64) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	task, worker, ok := tq.fencedTask(taskID, workerID, token)
+	if !ok {
+		return false
+	}
+	tq.complete(task, worker)
+	return true
+}
+
+// FailTaskWithToken is FailTaskWithReason for the assignment with the given
+// FencingToken only.
+func (tq *TaskQueue) FailTaskWithToken(taskID, workerID string, token uint64, reason string) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	task, worker, ok := tq.fencedTask(taskID, workerID, token)
+	if !ok {
+		return false
+	}
+	tq.fail(task, worker, reason)
+	return true
+}
+
+// fencedTask is runningTask for the assignment with the given token.
+func (tq *TaskQueue) fencedTask(taskID, workerID string, token uint64) (*Task, *Worker, bool) {
+	task, worker, ok := tq.runningTask(taskID, workerID)
+	if !ok || task.FencingToken != token {
+		return nil, nil, false
+	}
+	return task, worker, true
+}
+
+// keepLease heartbeats a task while the runtime runs its handler, and
+// cancels the handler if the task is reclaimed. It ticks in real time,
+// whatever the queue's Clock.
+func (tq *TaskQueue) keepLease(ctx context.Context, cancel context.CancelFunc, a assignment) {
+	ticker := time.NewTicker(max(tq.leaseDuration/3, minHeartbeatInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		tq.mu.Lock()
+		current := a.current()
+		if current {
+			tq.extendLease(a.task)
+		}
+		tq.unlock()
+		if !current {
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package taskqueue
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLeaseExpiryAndHeartbeat(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tq := NewTaskQueue(WithClock(clock), WithLeaseDuration(time.Minute))
+	tq.AddWorker("w1", "Worker One", 5)
+	healthy, _ := tq.SubmitTask("Healthy", PriorityHigh, 3)
+	stuck, _ := tq.SubmitTask("Stuck", PriorityHigh, 3)
+	tq.AssignTask()
+	tq.AssignTask()
+
+	if got := tq.GetTask(healthy).LeaseExpiresAt; !got.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("expected the lease to expire in a minute, got %v", got)
+	}
+
+	// Running for well over a lease, but heartbeating all along
+	for i := 0; i < 5; i++ {
+		clock.Advance(40 * time.Second)
+		if !tq.Heartbeat(healthy, "w1") {
+			t.Fatal("expected Heartbeat to succeed")
+		}
+		if i == 0 && tq.ReclaimExpiredLeases() != 0 {
+			t.Error("expected no lease to have expired yet")
+		}
+	}
+	if count := tq.ReclaimExpiredLeases(); count != 1 {
+		t.Errorf("expected 1 reclaimed, got %d", count)
+	}
+
+	if task := tq.GetTask(healthy); task.Status != StatusRunning || task.WorkerID != "w1" {
+		t.Errorf("expected the heartbeated task to keep running, got %s on %q", task.Status, task.WorkerID)
+	}
+	task := tq.GetTask(stuck)
+	if task.Status != StatusPending || task.WorkerID != "" || task.RetryCount != 0 || !task.LeaseExpiresAt.IsZero() {
+		t.Errorf("expected the expired task pending with no retry used, got %+v", task)
+	}
+	if tq.GetWorker("w1").TaskCount != 1 {
+		t.Errorf("expected 1 task left on the worker, got %d", tq.GetWorker("w1").TaskCount)
+	}
+	if tq.Heartbeat(stuck, "w1") {
+		t.Error("expected Heartbeat to fail for a reclaimed task")
+	}
+	if tq.Heartbeat(healthy, "w2") || tq.Heartbeat("TASK-999", "w1") {
+		t.Error("expected Heartbeat to fail for another worker or an unknown task")
+	}
+}
+
+func TestFencingTokenRejectsStaleOwner(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tq := NewTaskQueue(WithClock(clock), WithLeaseDuration(time.Minute))
+	tq.AddWorker("w1", "Worker One", 1)
+	id, _ := tq.SubmitTask("Task", PriorityHigh, 3)
+
+	_, _, stale, _ := tq.AssignTaskWithToken()
+	if stale != tq.GetTask(id).FencingToken {
+		t.Errorf("expected AssignTaskWithToken to return the task's token %d, got %d", tq.GetTask(id).FencingToken, stale)
+	}
+	clock.Advance(2 * time.Minute)
+	tq.ReclaimExpiredLeases()
+
+	// Reassigned to the same worker: only the token tells the owners apart
+	_, _, current, _ := tq.AssignTaskWithToken()
+	if current <= stale {
+		t.Fatalf("expected a new, higher token, got %d after %d", current, stale)
+	}
+	if tq.CompleteTaskWithToken(id, "w1", stale) {
+		t.Error("expected the previous owner's CompleteTaskWithToken to be rejected")
+	}
+	if tq.FailTaskWithToken(id, "w1", stale, "late") {
+		t.Error("expected the previous owner's FailTaskWithToken to be rejected")
+	}
+	if tq.HeartbeatWithToken(id, "w1", stale) {
+		t.Error("expected the previous owner's HeartbeatWithToken to be rejected")
+	}
+	if tq.GetTask(id).Status != StatusRunning {
+		t.Fatal("expected the task to still run for the current owner")
+	}
+	if !tq.HeartbeatWithToken(id, "w1", current) {
+		t.Error("expected the current owner's HeartbeatWithToken to succeed")
+	}
+	if !tq.CompleteTaskWithToken(id, "w1", current) {
+		t.Error("expected the current owner's CompleteTaskWithToken to succeed")
+	}
+}
+
+func TestReclaimedTaskSettlesForNewOwner(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tq := NewTaskQueue(WithClock(clock), WithLeaseDuration(time.Minute))
+	tq.AddWorker("w1", "Worker One", 1)
+	id, _ := tq.SubmitTask("Task", PriorityHigh, 3)
+	tq.AssignTask()
+
+	// Reclaimed while w1 is idle, a bigger worker joins and takes the task
+	clock.Advance(2 * time.Minute)
+	tq.ReclaimExpiredLeases()
+	tq.AddWorker("w2", "Worker Two", 5)
+	if _, workerID, _ := tq.AssignTask(); workerID != "w2" {
+		t.Fatalf("expected w2 to take the task, got %s", workerID)
+	}
+	if tq.CompleteTask(id, "w1") || tq.FailTask(id, "w1") || tq.Heartbeat(id, "w1") {
+		t.Error("expected the previous owner's calls to be rejected")
+	}
+	if !tq.Heartbeat(id, "w2") || !tq.CompleteTask(id, "w2") {
+		t.Error("expected the new owner's calls to succeed")
+	}
+
+	// Reassigned to the worker it was reclaimed from, without a token
+	id, _ = tq.SubmitTask("Again", PriorityHigh, 3)
+	tq.AssignTask()
+	clock.Advance(2 * time.Minute)
+	if tq.ReassignAbandonedTasks(time.Minute) != 1 {
+		t.Fatal("expected the task to be reassigned")
+	}
+	taskID, workerID, _ := tq.AssignTask()
+	if !tq.FailTaskWithReason(taskID, workerID, "flaky") {
+		t.Error("expected the reassigned owner's FailTaskWithReason to succeed")
+	}
+	tq.AssignTask()
+	if !tq.CompleteTask(id, tq.GetTask(id).WorkerID) {
+		t.Error("expected the reassigned owner's CompleteTask to succeed")
+	}
+}
+
+func TestRuntimeHeartbeatsLongHandlers(t *testing.T) {
+	tq := NewTaskQueue(WithLeaseDuration(30 * time.Millisecond))
+	tq.AddWorker("w1", "Worker One", 1)
+
+	var calls atomic.Int32
+	tq.SubmitTaskFunc("long", PriorityMedium, 0, func(ctx context.Context) error {
+		calls.Add(1)
+		select {
+		case <-time.After(150 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	tq.Start()
+	waitFor(t, func() bool { return completedCount(tq) == 1 })
+	shutdown(t, tq)
+
+	if calls.Load() != 1 {
+		t.Errorf("expected the handler to run once, got %d", calls.Load())
+	}
+}
+
+func TestRuntimeReclaimsExpiredLeases(t *testing.T) {
+	log := &eventLog{}
+	tq := NewTaskQueue(WithPublisher(log), WithLeaseDuration(20*time.Millisecond))
+	tq.AddWorker("w1", "Worker One", 1)
+	id, _ := tq.SubmitTask("manual", PriorityMedium, 0)
+
+	// Assigned by the runtime, but nobody heartbeats a task without a handler
+	tq.Start()
+	defer shutdown(t, tq)
+	waitFor(t, func() bool { return slices.Contains(eventNames(log.all()), EventTaskAbandoned) })
+
+	for _, event := range log.all() {
+		if got, ok := event.payload.(TaskAbandoned); ok && (got.TaskID != id || got.WorkerID != "w1") {
+			t.Errorf("unexpected abandoned payload %+v", got)
+		}
+	}
+}
+
+func TestRuntimeWithTinyLease(t *testing.T) {
+	tq := NewTaskQueue(WithLeaseDuration(time.Nanosecond))
+	tq.AddWorker("w1", "Worker One", 1)
+	var cancelled atomic.Int32
+	release := make(chan struct{})
+	tq.SubmitTaskFunc("long", PriorityMedium, 0, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return ctx.Err()
+		case <-release:
+			return nil
+		}
+	})
+
+	// A third of the lease is no ticker interval, but the lease is still
+	// watched and the handler cancelled once it is lost
+	tq.Start()
+	waitFor(t, func() bool { return cancelled.Load() > 0 })
+	close(release)
+	shutdown(t, tq)
+}
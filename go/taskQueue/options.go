@@ -0,0 +1,61 @@
+package taskqueue
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a TaskQueue.
+type Option func(*TaskQueue)
+
+// WithPublisher publishes an event for every task state change.
+func WithPublisher(p Publisher) Option {
+	return func(tq *TaskQueue) {
+		tq.publisher = p
+	}
+}
+
+// WithClock sets the time source. The default is RealClock.
+// The runtime's timers still run in real time; see Start.
+func WithClock(clock Clock) Option {
+	return func(tq *TaskQueue) {
+		tq.clock = clock
+	}
+}
+
+// WithDefaultRetryPolicy sets the backoff for tasks submitted without
+// WithRetryPolicy. By default a failed task may be assigned again at once.
+func WithDefaultRetryPolicy(policy RetryPolicy) Option {
+	return func(tq *TaskQueue) {
+		tq.retryPolicy = policy
+	}
+}
+
+// WithLeaseDuration sets how long an assignment lasts without a Heartbeat
+// before the task may be reclaimed. The default is DefaultLeaseDuration;
+// a d <= 0 keeps it.
+func WithLeaseDuration(d time.Duration) Option {
+	return func(tq *TaskQueue) {
+		if d > 0 {
+			tq.leaseDuration = d
+		}
+	}
+}
+
+// TaskOption configures a task when it is submitted.
+type TaskOption func(*Task)
+
+// WithRetryPolicy sets how long the task waits before each retry.
+func WithRetryPolicy(policy RetryPolicy) TaskOption {
+	return func(t *Task) {
+		t.RetryPolicy = policy
+	}
+}
+
+// WithHandler sets the function the worker runtime runs for the task, as
+// SubmitTaskFunc does; for use with SubmitTaskWithDeps.
+func WithHandler(handler func(ctx context.Context) error) TaskOption {
+	return func(t *Task) {
+		t.Handler = handler
+	}
+}
@@ -0,0 +1,167 @@
+package taskqueue
+
+import (
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryPolicy decides how long a failed task waits before it may be
+// assigned again. The zero value retries at once.
+type RetryPolicy struct {
+	Delay      time.Duration // wait before the first retry
+	Multiplier float64       // growth per retry; 1 or less keeps Delay fixed
+	Jitter     float64       // spreads each wait uniformly by up to this fraction either way, 0 to 1
+	MaxDelay   time.Duration // upper bound on any wait; 0 means none
+}
+
+// FixedBackoff waits delay before every retry.
+func FixedBackoff(delay time.Duration) RetryPolicy {
+	return RetryPolicy{Delay: delay}
+}
+
+// ExponentialBackoff doubles the wait on every retry, from base up to max,
+// with 20% jitter so tasks that failed together do not retry together.
+func ExponentialBackoff(base, max time.Duration) RetryPolicy {
+	return RetryPolicy{Delay: base, Multiplier: 2, Jitter: 0.2, MaxDelay: max}
+}
+
+// Backoff returns the wait before the given retry, counting from 1.
+// However many retries, it never exceeds MaxDelay, or the longest
+// time.Duration without one. Jitter applies to the capped wait, spreading it
+// below the cap.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	limit := float64(math.MaxInt64)
+	if p.MaxDelay > 0 {
+		limit = float64(p.MaxDelay)
+	}
+
+	// Clamped before the jitter, which turns an infinite delay into NaN
+	delay := float64(p.Delay)
+	if p.Delay > 0 && p.Multiplier > 1 && retry > 1 {
+		delay = min(delay*math.Pow(p.Multiplier, float64(retry-1)), limit)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	switch {
+	case delay < limit:
+		return time.Duration(delay)
+	case p.MaxDelay > 0:
+		return p.MaxDelay
+	default:
+		// float64(math.MaxInt64) rounds up to 2^63, which does not convert back
+		return math.MaxInt64
+	}
+}
+
+// FailedAttempt records one failed run of a task.
+type FailedAttempt struct {
+	WorkerID  string
+	Reason    string
+	StartedAt time.Time
+	FailedAt  time.Time
+}
+
+// delayedHeap holds pending tasks waiting out their backoff, the one due
+// first on top.
+type delayedHeap []*Task
+
+func (h delayedHeap) Len() int { return len(h) }
+
+func (h delayedHeap) Less(i, j int) bool {
+	return h[i].NextAttemptAt.Before(h[j].NextAttemptAt)
+}
+
+func (h delayedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *delayedHeap) Push(x any) {
+	task := x.(*Task)
+	task.heapIndex = len(*h)
+	*h = append(*h, task)
+}
+
+func (h *delayedHeap) Pop() any {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.heapIndex = -1
+	*h = old[:n-1]
+	return task
+}
+
+// promoteDue moves tasks whose backoff is over to the pending heap.
+// Callers must hold tq.mu.
+func (tq *TaskQueue) promoteDue(now time.Time) {
+	for len(tq.delayed) > 0 && !tq.delayed[0].NextAttemptAt.After(now) {
+		task := heap.Pop(&tq.delayed).(*Task)
+		heap.Push(&tq.pending, task)
+	}
+}
+
+// nextDue returns when the next delayed task may be assigned, if any.
+// Callers must hold tq.mu.
+func (tq *TaskQueue) nextDue() (time.Time, bool) {
+	if len(tq.delayed) == 0 {
+		return time.Time{}, false
+	}
+	return tq.delayed[0].NextAttemptAt, true
+}
+
+// FailTaskWithReason is FailTask that records why the attempt failed.
+// A retried task waits for its RetryPolicy's backoff: AssignTask skips it
+// until NextAttemptAt. A task with no retries left is failed and moved to
+// the dead-letter list.
+func (tq *TaskQueue) FailTaskWithReason(taskID, workerID, reason string) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	task, worker, ok := tq.runningTask(taskID, workerID)
+	if !ok {
+		return false
+	}
+	tq.fail(task, worker, reason)
+	return true
+}
+
+// GetDeadLetters returns the tasks that failed with no retries left, in the
+// order they failed.
+func (tq *TaskQueue) GetDeadLetters() []*Task {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	return slices.Clone(tq.deadLetters)
+}
+
+// RequeueDeadLetter takes a task off the dead-letter list and makes it
+// pending again with its retries reset. Its failed attempts are kept.
+// Dependents that failed because of it wait on it again, unless another of
+// their dependencies has failed too.
+// Returns false if the task is not on the list.
+func (tq *TaskQueue) RequeueDeadLetter(id string) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	i := slices.IndexFunc(tq.deadLetters, func(t *Task) bool { return t.ID == id })
+	if i < 0 {
+		return false
+	}
+	task := tq.deadLetters[i]
+	tq.deadLetters = slices.Delete(tq.deadLetters, i, i+1)
+
+	task.RetryCount = 0
+	task.NextAttemptAt = time.Time{}
+	task.CompletedAt = time.Time{}
+	tq.enqueue(task)
+	tq.publish(EventTaskRequeued, TaskRequeued{TaskID: id, At: tq.clock.Now()})
+	tq.reviveDependents(task)
+	tq.wake()
+	return true
+}
@@ -0,0 +1,185 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	fixed := FixedBackoff(time.Second)
+	for retry := 1; retry <= 3; retry++ {
+		if got := fixed.Backoff(retry); got != time.Second {
+			t.Errorf("fixed retry %d: expected 1s, got %v", retry, got)
+		}
+	}
+
+	exp := RetryPolicy{Delay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second}
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := exp.Backoff(retry); got != want {
+			t.Errorf("exponential retry %d: expected %v, got %v", retry, want, got)
+		}
+	}
+
+	jittered := ExponentialBackoff(time.Second, time.Minute)
+	for i := 0; i < 100; i++ {
+		got := jittered.Backoff(3)
+		if got < 3200*time.Millisecond || got > 4800*time.Millisecond {
+			t.Fatalf("expected 4s ±20%%, got %v", got)
+		}
+	}
+	// Capped waits are jittered too, so tasks that keep failing together spread out
+	for i := 0; i < 100; i++ {
+		if got := jittered.Backoff(20); got < 48*time.Second || got > time.Minute {
+			t.Fatalf("expected jitter to stay within MaxDelay, got %v", got)
+		}
+	}
+
+	// Past 2^63ns the wait would overflow into a negative Duration
+	unbounded := RetryPolicy{Delay: time.Second, Multiplier: 2}
+	for _, retry := range []int{35, 1000, math.MaxInt} {
+		if got := unbounded.Backoff(retry); got != math.MaxInt64 {
+			t.Errorf("retry %d: expected the longest Duration, got %v", retry, got)
+		}
+	}
+	if got := exp.Backoff(1000); got != 5*time.Second {
+		t.Errorf("expected MaxDelay for a large retry count, got %v", got)
+	}
+	for i := 0; i < 100; i++ {
+		if got := jittered.Backoff(2000); got < 48*time.Second || got > time.Minute {
+			t.Fatalf("expected a jittered MaxDelay for a large retry count, got %v", got)
+		}
+		got := RetryPolicy{Delay: time.Second, Multiplier: 2, Jitter: 0.2}.Backoff(math.MaxInt)
+		if got < math.MaxInt64/10*8 {
+			t.Fatalf("expected a jittered longest Duration, got %v", got)
+		}
+	}
+
+	if got := (RetryPolicy{}).Backoff(1); got != 0 {
+		t.Errorf("expected the zero policy to retry at once, got %v", got)
+	}
+}
+
+func TestAssignTaskWaitsOutBackoff(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tq := NewTaskQueue(WithClock(clock), WithDefaultRetryPolicy(FixedBackoff(time.Minute)))
+	tq.AddWorker("w1", "Worker One", 2)
+	id, _ := tq.SubmitTask("Flaky", PriorityHigh, 3)
+	other, _ := tq.SubmitTask("Other", PriorityLow, 3)
+
+	taskID, workerID, _ := tq.AssignTask()
+	if !tq.FailTaskWithReason(taskID, workerID, "timeout") {
+		t.Fatal("expected FailTaskWithReason to succeed")
+	}
+
+	task := tq.GetTask(id)
+	if task.Status != StatusPending || !task.NextAttemptAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("expected pending until a minute from now, got %s until %v", task.Status, task.NextAttemptAt)
+	}
+	if len(tq.GetPendingTasks()) != 2 {
+		t.Error("expected the backing-off task to be listed as pending")
+	}
+
+	// The lower priority task goes first while the retry waits
+	if taskID, _, _ := tq.AssignTask(); taskID != other {
+		t.Errorf("expected %s, got %s", other, taskID)
+	}
+	if _, _, ok := tq.AssignTask(); ok {
+		t.Error("expected no assignment before NextAttemptAt")
+	}
+
+	clock.Advance(time.Minute)
+	if taskID, _, ok := tq.AssignTask(); !ok || taskID != id {
+		t.Errorf("expected %s once its backoff is over, got %s", id, taskID)
+	}
+}
+
+func TestPerTaskRetryPolicy(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tq := NewTaskQueue(WithClock(clock), WithDefaultRetryPolicy(FixedBackoff(time.Hour)))
+	tq.AddWorker("w1", "Worker One", 1)
+	id, _ := tq.SubmitTask("Quick", PriorityHigh, 3, WithRetryPolicy(RetryPolicy{}))
+
+	taskID, workerID, _ := tq.AssignTask()
+	tq.FailTask(taskID, workerID)
+
+	if !tq.GetTask(id).NextAttemptAt.IsZero() {
+		t.Error("expected the task's own policy to retry at once")
+	}
+	if taskID, _, ok := tq.AssignTask(); !ok || taskID != id {
+		t.Errorf("expected %s to be assigned again at once, got %s", id, taskID)
+	}
+}
+
+func TestFailureReasonsAndDeadLetters(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tq := NewTaskQueue(WithClock(clock))
+	tq.AddWorker("w1", "Worker One", 1)
+	id, _ := tq.SubmitTask("Doomed", PriorityHigh, 1)
+
+	for _, reason := range []string{"disk full", "still full"} {
+		taskID, workerID, _ := tq.AssignTask()
+		clock.Advance(time.Second)
+		tq.FailTaskWithReason(taskID, workerID, reason)
+	}
+
+	task := tq.GetTask(id)
+	if task.Status != StatusFailed {
+		t.Fatalf("expected failed, got %s", task.Status)
+	}
+	if len(task.Failures) != 2 || task.Failures[0].Reason != "disk full" || task.Failures[1].Reason != "still full" {
+		t.Errorf("expected both failure reasons recorded, got %+v", task.Failures)
+	}
+	if f := task.Failures[1]; f.WorkerID != "w1" || f.FailedAt.Sub(f.StartedAt) != time.Second {
+		t.Errorf("expected the attempt's worker and times, got %+v", f)
+	}
+
+	dead := tq.GetDeadLetters()
+	if len(dead) != 1 || dead[0].ID != id {
+		t.Fatalf("expected %s on the dead-letter list, got %v", id, dead)
+	}
+
+	if tq.RequeueDeadLetter("TASK-999") {
+		t.Error("expected RequeueDeadLetter to reject an unknown task")
+	}
+	if !tq.RequeueDeadLetter(id) {
+		t.Fatal("expected RequeueDeadLetter to succeed")
+	}
+	if len(tq.GetDeadLetters()) != 0 {
+		t.Error("expected the dead-letter list to be empty")
+	}
+	if task.Status != StatusPending || task.RetryCount != 0 || len(task.Failures) != 2 {
+		t.Errorf("expected pending with retries reset and history kept, got %s, %d retries, %d failures",
+			task.Status, task.RetryCount, len(task.Failures))
+	}
+	if taskID, _, ok := tq.AssignTask(); !ok || taskID != id {
+		t.Errorf("expected the requeued task to be assigned, got %s", taskID)
+	}
+}
+
+func TestRuntimeRetriesAfterBackoff(t *testing.T) {
+	tq := NewTaskQueue(WithDefaultRetryPolicy(FixedBackoff(20 * time.Millisecond)))
+	tq.AddWorker("w1", "Worker One", 1)
+
+	var attempts []time.Time
+	id, _ := tq.SubmitTaskFunc("flaky", PriorityHigh, 1, func(ctx context.Context) error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+
+	tq.Start()
+	waitFor(t, func() bool { return completedCount(tq) == 1 })
+	shutdown(t, tq)
+
+	if len(attempts) != 2 || attempts[1].Sub(attempts[0]) < 20*time.Millisecond {
+		t.Errorf("expected a second attempt after the backoff, got %v", attempts)
+	}
+	if task := tq.GetTask(id); len(task.Failures) != 1 || task.Failures[0].Reason != "not yet" {
+		t.Errorf("expected the handler's error as the reason, got %+v", task.Failures)
+	}
+}
@@ -0,0 +1,272 @@
+package taskqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// runtime is the state of a started queue.
+type runtime struct {
+	ctx    context.Context // passed to handlers, cancelled when Shutdown gives up waiting
+	cancel context.CancelFunc
+	wakeCh chan struct{}
+	stop   chan struct{}     // closed by Shutdown: no new tasks start
+	done   chan struct{}     // closed when the dispatcher exits
+	jobs   []chan assignment // one per worker pool
+	pools  sync.WaitGroup
+}
+
+// assignment is a task handed to a worker pool. token tells a stale
+// assignment apart once the task was settled or reclaimed and assigned again.
+type assignment struct {
+	task   *Task
+	worker *Worker
+	token  uint64
+}
+
+// Start runs task handlers. Every worker becomes a pool of MaxTasks
+// goroutines, and pending tasks are assigned automatically whenever a worker
+// has capacity, in AssignTask's order. A handler returning nil completes its
+// task with CompleteTask's transitions; an error or panic fails it like
+// FailTask. Tasks without a Handler are assigned too, and left for the
+// caller to complete or fail.
+// The runtime heartbeats the tasks whose handlers it runs, and reclaims
+// tasks whose lease expired, cancelling the context of a handler that lost
+// its lease.
+// Only the deadlines come from the queue's Clock: the runtime waits for them,
+// and heartbeats every third of the lease duration, on real timers. With a
+// clock that does not keep pace with the wall clock, such as a fake one in
+// tests, it reassigns retries and reclaims leases late or early; drive such
+// a queue with AssignTask and ReclaimExpiredLeases instead.
+// Returns false if the queue is already started.
+func (tq *TaskQueue) Start() bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	if tq.runtime != nil {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rt := &runtime{
+		ctx:    ctx,
+		cancel: cancel,
+		wakeCh: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	tq.runtime = rt
+	for _, worker := range tq.workers {
+		rt.startPool(tq, worker)
+	}
+	go tq.dispatch(rt)
+	tq.wake()
+	return true
+}
+
+// Shutdown stops assigning tasks and waits for running handlers to return.
+// If ctx ends first, the handlers' context is cancelled and Shutdown waits
+// for them to return before it returns ctx.Err(). A handler that returns an
+// error after that cancellation puts its task back to pending without using
+// a retry, as do tasks that were assigned but not started yet.
+// The queue may be started again afterwards.
+func (tq *TaskQueue) Shutdown(ctx context.Context) error {
+	tq.mu.Lock()
+	rt := tq.runtime
+	tq.runtime = nil
+	tq.unlock()
+	if rt == nil {
+		return nil
+	}
+
+	close(rt.stop)
+	<-rt.done
+	for _, jobs := range rt.jobs {
+		close(jobs)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		rt.pools.Wait()
+		close(finished)
+	}()
+
+	defer rt.cancel()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		rt.cancel()
+		<-finished
+		return ctx.Err()
+	}
+}
+
+// wake tells the dispatcher to look for work. Callers must hold tq.mu.
+func (tq *TaskQueue) wake() {
+	if tq.runtime == nil {
+		return
+	}
+	select {
+	case tq.runtime.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// startPool starts MaxTasks goroutines running the worker's tasks.
+// Callers must hold tq.mu.
+func (rt *runtime) startPool(tq *TaskQueue, worker *Worker) {
+	jobs := make(chan assignment, worker.MaxTasks)
+	worker.jobs = jobs
+	rt.jobs = append(rt.jobs, jobs)
+	for i := 0; i < worker.MaxTasks; i++ {
+		rt.pools.Add(1)
+		go func() {
+			defer rt.pools.Done()
+			for a := range jobs {
+				tq.run(rt, a)
+			}
+		}()
+	}
+}
+
+// dispatch reclaims expired leases and assigns tasks whenever it is woken,
+// until Shutdown.
+func (tq *TaskQueue) dispatch(rt *runtime) {
+	defer close(rt.done)
+
+	for {
+		tq.mu.Lock()
+		tq.reclaimExpired(tq.clock.Now())
+		assigned := []assignment{}
+		for {
+			task, worker := tq.assign()
+			if task == nil {
+				break
+			}
+			if task.Handler != nil {
+				assigned = append(assigned, assignment{task, worker, task.FencingToken})
+			}
+		}
+		// Look again when the next retry backoff ends or lease expires
+		var timer *time.Timer
+		var due <-chan time.Time
+		if next, ok := tq.nextTimeout(); ok {
+			timer = time.NewTimer(next.Sub(tq.clock.Now()))
+			due = timer.C
+		}
+		tq.unlock()
+
+		// Sent without the lock: a full pool drains as its handlers settle
+		for i, a := range assigned {
+			select {
+			case a.worker.jobs <- a:
+			case <-rt.stop:
+				for _, a := range assigned[i:] {
+					tq.release(a)
+				}
+				return
+			}
+		}
+
+		select {
+		case <-rt.wakeCh:
+		case <-due:
+		case <-rt.stop:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if rt.stopped() {
+			return
+		}
+	}
+}
+
+// run calls a task's handler and settles the task with the outcome.
+func (tq *TaskQueue) run(rt *runtime, a assignment) {
+	if rt.stopped() {
+		tq.release(a)
+		return
+	}
+
+	tq.mu.Lock()
+	current := a.current()
+	tq.unlock()
+	if !current {
+		return // settled by the caller meanwhile
+	}
+
+	ctx, cancel := context.WithCancel(rt.ctx)
+	defer cancel()
+	go tq.keepLease(ctx, cancel, a)
+	err := callHandler(ctx, a.task.Handler)
+	cancel()
+
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	if !a.current() {
+		return
+	}
+	switch {
+	case err == nil:
+		tq.complete(a.task, a.worker)
+	case rt.ctx.Err() != nil:
+		tq.requeue(a.task, a.worker)
+	default:
+		tq.fail(a.task, a.worker, err.Error())
+	}
+}
+
+// stopped reports whether Shutdown has begun.
+func (rt *runtime) stopped() bool {
+	select {
+	case <-rt.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// current reports whether the task is still running for this assignment.
+// Callers must hold tq.mu.
+func (a assignment) current() bool {
+	return a.task.Status == StatusRunning && a.task.WorkerID == a.worker.ID && a.task.FencingToken == a.token
+}
+
+// release puts back a task that was assigned but never started.
+func (tq *TaskQueue) release(a assignment) {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	if a.current() {
+		tq.requeue(a.task, a.worker)
+	}
+}
+
+// requeue takes a running task from its worker and makes it pending again
+// without using a retry. Callers must hold tq.mu.
+func (tq *TaskQueue) requeue(task *Task, worker *Worker) {
+	tq.unassign(task, worker)
+	tq.publish(EventTaskAbandoned, TaskAbandoned{
+		TaskID:   task.ID,
+		WorkerID: worker.ID,
+		At:       tq.clock.Now(),
+	})
+	task.WorkerID = ""
+	task.StartedAt = time.Time{}
+	tq.enqueue(task)
+	tq.wake()
+}
+
+// callHandler runs a handler, turning a panic into an error.
+func callHandler(ctx context.Context, handler func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("taskqueue: handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx)
+}
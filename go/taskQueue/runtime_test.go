@@ -0,0 +1,238 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func completedCount(tq *TaskQueue) int {
+	_, _, completed, _ := tq.GetQueueStats()
+	return completed
+}
+
+func shutdown(t *testing.T, tq *TaskQueue) {
+	t.Helper()
+	if err := tq.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}
+
+func TestRuntimeRunsHandlers(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 2)
+
+	var running, maxRunning, calls atomic.Int32
+	handler := func(ctx context.Context) error {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		calls.Add(1)
+		return nil
+	}
+	for i := 0; i < 6; i++ {
+		tq.SubmitTaskFunc("job", PriorityMedium, 0, handler)
+	}
+
+	if !tq.Start() {
+		t.Fatal("expected Start to succeed")
+	}
+	if tq.Start() {
+		t.Error("expected a second Start to fail")
+	}
+	waitFor(t, func() bool { return completedCount(tq) == 6 })
+	shutdown(t, tq)
+
+	if calls.Load() != 6 {
+		t.Errorf("expected 6 handler calls, got %d", calls.Load())
+	}
+	if maxRunning.Load() != 2 {
+		t.Errorf("expected the worker to run 2 tasks at a time, got %d", maxRunning.Load())
+	}
+	if tq.GetWorker("w1").TaskCount != 0 {
+		t.Errorf("expected no tasks left on the worker, got %d", tq.GetWorker("w1").TaskCount)
+	}
+}
+
+func TestRuntimeRetriesFailedHandlers(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+
+	var calls atomic.Int32
+	flaky, _ := tq.SubmitTaskFunc("flaky", PriorityHigh, 3, func(ctx context.Context) error {
+		if calls.Add(1) < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	broken, _ := tq.SubmitTaskFunc("broken", PriorityLow, 0, func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	tq.Start()
+	waitFor(t, func() bool {
+		_, _, completed, failed := tq.GetQueueStats()
+		return completed == 1 && failed == 1
+	})
+	shutdown(t, tq)
+
+	if task := tq.GetTask(flaky); task.Status != StatusCompleted || task.RetryCount != 2 {
+		t.Errorf("expected flaky to complete after 2 retries, got %s after %d", task.Status, task.RetryCount)
+	}
+	if task := tq.GetTask(broken); task.Status != StatusFailed {
+		t.Errorf("expected a panicking handler to fail its task, got %s", task.Status)
+	}
+}
+
+func TestRuntimeAssignsAsCapacityFreesUp(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.Start()
+	defer shutdown(t, tq)
+
+	release := make(chan struct{})
+	blocking := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+	tq.SubmitTaskFunc("first", PriorityMedium, 0, blocking)
+	second, _ := tq.SubmitTaskFunc("second", PriorityMedium, 0, blocking)
+
+	// No workers yet; one added while running gets a pool of its own
+	tq.AddWorker("w1", "Worker One", 1)
+	waitFor(t, func() bool { return len(tq.GetWorkerTasks("w1")) == 1 })
+	if tq.GetTask(second).Status != StatusPending {
+		t.Error("expected the second task to wait for capacity")
+	}
+
+	close(release)
+	waitFor(t, func() bool { return completedCount(tq) == 2 })
+}
+
+func TestRuntimeLeavesTasksWithoutHandler(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	manual, _ := tq.SubmitTask("manual", PriorityHigh, 0)
+	auto, _ := tq.SubmitTaskFunc("auto", PriorityLow, 0, func(ctx context.Context) error { return nil })
+
+	tq.Start()
+	defer shutdown(t, tq)
+	waitFor(t, func() bool { return len(tq.GetWorkerTasks("w1")) == 1 })
+
+	if !tq.CompleteTask(manual, "w1") {
+		t.Fatal("expected the caller to complete the task without a handler")
+	}
+	waitFor(t, func() bool { return completedCount(tq) == 2 })
+	if tq.GetTask(auto).Status != StatusCompleted {
+		t.Error("expected the handler task to run once capacity freed up")
+	}
+}
+
+func TestShutdownWaitsForHandlers(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	id, _ := tq.SubmitTaskFunc("slow", PriorityMedium, 0, func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	tq.Start()
+	<-started
+
+	var returned atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		shutdown(t, tq)
+		returned.Store(true)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if returned.Load() {
+		t.Error("expected Shutdown to wait for the running handler")
+	}
+	close(release)
+	wg.Wait()
+	if tq.GetTask(id).Status != StatusCompleted {
+		t.Errorf("expected the task to complete, got %s", tq.GetTask(id).Status)
+	}
+}
+
+func TestShutdownCancelsHandlers(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	started := make(chan struct{})
+	id, _ := tq.SubmitTaskFunc("endless", PriorityMedium, 3, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	tq.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tq.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+
+	// Interrupted by the shutdown, not failed: pending again with no retry used
+	task := tq.GetTask(id)
+	if task.Status != StatusPending || task.RetryCount != 0 || task.WorkerID != "" {
+		t.Errorf("expected the task back to pending, got %s, %d retries, worker %q", task.Status, task.RetryCount, task.WorkerID)
+	}
+	if tq.GetWorker("w1").TaskCount != 0 {
+		t.Errorf("expected the worker to be free, got %d tasks", tq.GetWorker("w1").TaskCount)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	tq := NewTaskQueue()
+	for _, id := range []string{"w1", "w2", "w3"} {
+		tq.AddWorker(id, id, 4)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tq.SubmitTask("task", Priority(i%3+1), 0)
+				if taskID, workerID, ok := tq.AssignTask(); ok {
+					tq.CompleteTask(taskID, workerID)
+				}
+				tq.GetPendingTasks()
+			}
+		}()
+	}
+	wg.Wait()
+
+	pending, running, completed, _ := tq.GetQueueStats()
+	if running != 0 || pending+completed != 800 {
+		t.Errorf("expected 800 tasks pending or completed, got %d pending, %d running, %d completed", pending, running, completed)
+	}
+}
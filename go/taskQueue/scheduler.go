@@ -0,0 +1,121 @@
+package taskqueue
+
+import (
+	"cmp"
+	"container/heap"
+)
+
+// pendingHeap holds the pending tasks, next to assign first: highest
+// Priority, then earliest CreatedAt, then earliest submitted.
+type pendingHeap []*Task
+
+func (h pendingHeap) Len() int { return len(h) }
+
+func (h pendingHeap) Less(i, j int) bool {
+	return compareTasks(h[i], h[j]) < 0
+}
+
+func (h pendingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *pendingHeap) Push(x any) {
+	task := x.(*Task)
+	task.heapIndex = len(*h)
+	*h = append(*h, task)
+}
+
+func (h *pendingHeap) Pop() any {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.heapIndex = -1
+	*h = old[:n-1]
+	return task
+}
+
+// compareTasks orders tasks the way AssignTask takes them.
+func compareTasks(a, b *Task) int {
+	if a.Priority != b.Priority {
+		return cmp.Compare(b.Priority, a.Priority)
+	}
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.seq, b.seq)
+}
+
+// workerHeap holds the active workers, the one with the most free capacity
+// first; ties go to the worker added earliest.
+type workerHeap []*Worker
+
+func (h workerHeap) Len() int { return len(h) }
+
+func (h workerHeap) Less(i, j int) bool {
+	fi, fj := h[i].freeCapacity(), h[j].freeCapacity()
+	if fi != fj {
+		return fi > fj
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h workerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *workerHeap) Push(x any) {
+	worker := x.(*Worker)
+	worker.heapIndex = len(*h)
+	*h = append(*h, worker)
+}
+
+func (h *workerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	worker := old[n-1]
+	old[n-1] = nil
+	worker.heapIndex = -1
+	*h = old[:n-1]
+	return worker
+}
+
+func (w *Worker) freeCapacity() int {
+	return w.MaxTasks - w.TaskCount
+}
+
+// enqueue makes a task pending: eligible for assignment now, once its
+// dependencies have completed, or once its NextAttemptAt has passed.
+func (tq *TaskQueue) enqueue(task *Task) {
+	task.Status = StatusPending
+	if task.waitingOn > 0 {
+		tq.blocked[task.ID] = task
+		return
+	}
+	if task.NextAttemptAt.After(tq.clock.Now()) {
+		heap.Push(&tq.delayed, task)
+		return
+	}
+	heap.Push(&tq.pending, task)
+}
+
+// availableWorker returns the active worker with the most free capacity,
+// or nil if every active worker is full.
+func (tq *TaskQueue) availableWorker() *Worker {
+	if len(tq.available) == 0 || tq.available[0].freeCapacity() <= 0 {
+		return nil
+	}
+	return tq.available[0]
+}
+
+// setTaskCount changes a worker's TaskCount and keeps the worker index in order.
+func (tq *TaskQueue) setTaskCount(worker *Worker, count int) {
+	worker.TaskCount = count
+	if worker.heapIndex >= 0 {
+		heap.Fix(&tq.available, worker.heapIndex)
+	}
+}
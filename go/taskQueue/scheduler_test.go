@@ -0,0 +1,131 @@
+package taskqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignTaskSameCreatedAtUsesSubmissionOrder(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 10)
+
+	created := time.Now()
+	for _, name := range []string{"First", "Second", "Third"} {
+		id, _ := tq.SubmitTask(name, PriorityMedium, 3)
+		tq.GetTask(id).CreatedAt = created
+	}
+
+	for _, want := range []string{"First", "Second", "Third"} {
+		taskID, _, _ := tq.AssignTask()
+		if got := tq.GetTask(taskID).Name; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestAssignTaskPrefersMostFreeWorker(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("small", "Small", 1)
+	tq.AddWorker("big", "Big", 3)
+	for i := 0; i < 4; i++ {
+		tq.SubmitTask("Task", PriorityMedium, 3)
+	}
+
+	// big has 3, 2 free; then big and small tie at 1 and small was added first
+	want := []string{"big", "big", "small", "big"}
+	for i, w := range want {
+		_, workerID, ok := tq.AssignTask()
+		if !ok || workerID != w {
+			t.Errorf("assignment %d: expected %s, got %s", i, w, workerID)
+		}
+	}
+	if _, _, ok := tq.AssignTask(); ok {
+		t.Error("expected no assignment with every worker full")
+	}
+}
+
+func TestAssignTaskAfterCapacityFreesUp(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	tq.AddWorker("w2", "Worker Two", 1)
+	tq.SubmitTask("Task 1", PriorityHigh, 3)
+	tq.SubmitTask("Task 2", PriorityHigh, 3)
+	tq.SubmitTask("Task 3", PriorityHigh, 0)
+
+	tq.AssignTask()
+	taskID, workerID, _ := tq.AssignTask()
+	tq.CompleteTask(taskID, workerID)
+
+	if _, got, ok := tq.AssignTask(); !ok || got != workerID {
+		t.Errorf("expected %s to get the next task once free, got %s", workerID, got)
+	}
+
+	// Reactivated workers rejoin the index with their current load
+	tq.SetWorkerActive("w1", false)
+	tq.SetWorkerActive("w1", true)
+	tq.SubmitTask("Task 4", PriorityHigh, 3)
+	if _, _, ok := tq.AssignTask(); ok {
+		t.Error("expected no assignment while both workers are full")
+	}
+}
+
+func TestRetriedTaskKeepsItsPlace(t *testing.T) {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	first, _ := tq.SubmitTask("First", PriorityMedium, 3)
+	time.Sleep(time.Millisecond)
+	tq.SubmitTask("Second", PriorityMedium, 3)
+
+	taskID, workerID, _ := tq.AssignTask()
+	tq.FailTask(taskID, workerID)
+
+	if taskID, _, _ := tq.AssignTask(); taskID != first {
+		t.Errorf("expected the retried task to be assigned before later ones, got %s", taskID)
+	}
+}
+
+const benchmarkHistory = 1_000_000
+
+// newBusyQueue returns a queue with benchmarkHistory completed tasks and
+// pending tasks waiting, and a worker with room for one more task.
+func newBusyQueue(pending int) *TaskQueue {
+	tq := NewTaskQueue()
+	tq.AddWorker("w1", "Worker One", 1)
+	for i := 0; i < benchmarkHistory; i++ {
+		tq.SubmitTask("done", Priority(i%3+1), 0)
+		taskID, workerID, _ := tq.AssignTask()
+		tq.CompleteTask(taskID, workerID)
+	}
+	for i := 0; i < pending; i++ {
+		tq.SubmitTask("waiting", Priority(i%3+1), 0)
+	}
+	return tq
+}
+
+func BenchmarkAssignTask1MHistory(b *testing.B) {
+	tq := newBusyQueue(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tq.SubmitTask("task", PriorityMedium, 0)
+		taskID, workerID, _ := tq.AssignTask()
+		tq.CompleteTask(taskID, workerID)
+	}
+}
+
+func BenchmarkAssignTask1MPending(b *testing.B) {
+	tq := newBusyQueue(benchmarkHistory)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		taskID, workerID, _ := tq.AssignTask()
+		tq.CompleteTask(taskID, workerID)
+		tq.SubmitTask("task", Priority(i%3+1), 0)
+	}
+}
+
+func BenchmarkGetPendingTasks1MHistory(b *testing.B) {
+	tq := newBusyQueue(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tq.GetPendingTasks()
+	}
+}
@@ -1,8 +1,11 @@
 package taskqueue
 
 import (
+	"container/heap"
+	"context"
 	"fmt"
 	"slices"
+	"sync"
 	"time"
 )
 
@@ -34,6 +37,20 @@ type Task struct {
 	CompletedAt time.Time
 	RetryCount  int
 	MaxRetries  int
+	Handler     func(ctx context.Context) error // run by the worker runtime; nil for tasks completed by the caller
+
+	RetryPolicy   RetryPolicy
+	NextAttemptAt time.Time       // a retried task is not assigned before this
+	Failures      []FailedAttempt // oldest first
+	DependsOn     []string        // tasks that must complete before this one may run
+
+	LeaseExpiresAt time.Time // while running: when the task may be reclaimed, unless Heartbeat extends it
+	FencingToken   uint64    // identifies the latest assignment; see CompleteTaskWithToken
+
+	seq       int     // submission order, the last tie-breaker for assignment
+	heapIndex int     // position in the pending, delayed or lease heap, -1 when in none
+	waitingOn int     // dependencies not completed yet
+	children  []*Task // tasks that depend on this one
 }
 
 type Worker struct {
@@ -42,37 +59,76 @@ type Worker struct {
 	IsActive  bool
 	TaskCount int
 	MaxTasks  int
+
+	seq       int             // registration order, breaks ties between equally free workers
+	heapIndex int             // position in the available heap, -1 when inactive
+	jobs      chan assignment // set while the runtime is started
 }
 
+// TaskQueue is safe for concurrent use. Tasks and workers returned by its
+// methods are live: read them only while nothing else changes the queue.
 type TaskQueue struct {
 	tasks       map[string]*Task
 	workers     map[string]*Worker
 	lastTaskSeq int
-	// Add fields as needed
+	mu          sync.Mutex
+
+	publisher Publisher
+	outgoing  []outgoingEvent // published once mu is released
+
+	clock         Clock
+	retryPolicy   RetryPolicy
+	leaseDuration time.Duration
+	lastToken     uint64
+
+	pending     pendingHeap      // pending tasks that may run now, next to assign first
+	delayed     delayedHeap      // pending tasks waiting out a retry backoff, due first
+	blocked     map[string]*Task // pending tasks waiting on their dependencies
+	leases      leaseHeap        // running tasks, lease expiring first
+	deadLetters []*Task          // failed with no retries left, in the order they failed
+	available   workerHeap       // active workers, most free capacity first
+	runtime     *runtime         // set while Start is in effect
 }
 
-func NewTaskQueue() *TaskQueue {
-	return &TaskQueue{
-		tasks:       make(map[string]*Task),
-		workers:     make(map[string]*Worker),
-		lastTaskSeq: 0,
+func NewTaskQueue(opts ...Option) *TaskQueue {
+	tq := &TaskQueue{
+		tasks:         make(map[string]*Task),
+		workers:       make(map[string]*Worker),
+		blocked:       make(map[string]*Task),
+		lastTaskSeq:   0,
+		clock:         RealClock{},
+		leaseDuration: DefaultLeaseDuration,
+	}
+	for _, opt := range opts {
+		opt(tq)
 	}
+	return tq
 }
 
 // AddWorker adds a new worker to the system.
 // Returns false if worker ID already exists or maxTasks <= 0.
 func (tq *TaskQueue) AddWorker(id, name string, maxTasks int) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
 	_, ok := tq.workers[id]
 	if ok || maxTasks <= 0 {
 		return false
 	}
-	tq.workers[id] = &Worker{
+	worker := &Worker{
 		ID:        id,
 		Name:      name,
 		IsActive:  true,
 		TaskCount: 0,
 		MaxTasks:  maxTasks,
+		seq:       len(tq.workers),
+	}
+	tq.workers[id] = worker
+	heap.Push(&tq.available, worker)
+	if tq.runtime != nil {
+		tq.runtime.startPool(tq, worker)
 	}
+	tq.wake()
 	return true
 }
 
@@ -80,16 +136,29 @@ func (tq *TaskQueue) AddWorker(id, name string, maxTasks int) bool {
 // Inactive workers cannot be assigned new tasks.
 // Returns false if worker doesn't exist.
 func (tq *TaskQueue) SetWorkerActive(id string, active bool) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
 	worker, ok := tq.workers[id]
 	if !ok {
 		return false
 	}
 	worker.IsActive = active
+	switch {
+	case active && worker.heapIndex < 0:
+		heap.Push(&tq.available, worker)
+	case !active && worker.heapIndex >= 0:
+		heap.Remove(&tq.available, worker.heapIndex)
+	}
+	tq.wake()
 	return true
 }
 
 // GetWorker returns a worker by ID, or nil if not found.
 func (tq *TaskQueue) GetWorker(id string) *Worker {
+	tq.mu.Lock()
+	defer tq.unlock()
+
 	return tq.workers[id]
 }
 
@@ -97,7 +166,19 @@ func (tq *TaskQueue) GetWorker(id string) *Worker {
 // Returns task ID (format: "TASK-{sequential number}") and true if successful.
 // Returns empty string and false if maxRetries < 0.
 // Default maxRetries is 3 if not specified (pass -1 to use default, 0 means no retries).
-func (tq *TaskQueue) SubmitTask(name string, priority Priority, maxRetries int) (string, bool) {
+func (tq *TaskQueue) SubmitTask(name string, priority Priority, maxRetries int, opts ...TaskOption) (string, bool) {
+	return tq.SubmitTaskFunc(name, priority, maxRetries, nil, opts...)
+}
+
+// SubmitTaskFunc is SubmitTask for a task the worker runtime runs: see Start.
+func (tq *TaskQueue) SubmitTaskFunc(name string, priority Priority, maxRetries int, handler func(ctx context.Context) error, opts ...TaskOption) (string, bool) {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	return tq.submit(name, priority, maxRetries, handler, nil, opts)
+}
+
+func (tq *TaskQueue) submit(name string, priority Priority, maxRetries int, handler func(ctx context.Context) error, parents []*Task, opts []TaskOption) (string, bool) {
 	if maxRetries == -1 {
 		maxRetries = 3
 	}
@@ -109,139 +190,234 @@ func (tq *TaskQueue) SubmitTask(name string, priority Priority, maxRetries int)
 	tq.lastTaskSeq++
 	newTaskId := fmt.Sprintf("TASK-%d", tq.lastTaskSeq)
 	newTask := &Task{
-		ID:         newTaskId,
-		Name:       name,
-		Priority:   priority,
-		Status:     StatusPending,
-		MaxRetries: maxRetries,
-		CreatedAt:  time.Now(),
+		ID:          newTaskId,
+		Name:        name,
+		Priority:    priority,
+		MaxRetries:  maxRetries,
+		CreatedAt:   tq.clock.Now(),
+		Handler:     handler,
+		RetryPolicy: tq.retryPolicy,
+		seq:         tq.lastTaskSeq,
+	}
+	for _, opt := range opts {
+		opt(newTask)
 	}
 	tq.tasks[newTaskId] = newTask
+	failedParent := tq.addDependencies(newTask, parents)
 
+	tq.publish(EventTaskSubmitted, TaskSubmitted{
+		TaskID:   newTaskId,
+		Name:     name,
+		Priority: priority,
+		At:       newTask.CreatedAt,
+	})
+	if failedParent != nil {
+		tq.failDependent(newTask, failedParent)
+		return newTaskId, true
+	}
+	tq.enqueue(newTask)
+	tq.wake()
 	return newTaskId, true
 }
 
 // GetTask returns a task by ID, or nil if not found.
 func (tq *TaskQueue) GetTask(id string) *Task {
+	tq.mu.Lock()
+	defer tq.unlock()
+
 	return tq.tasks[id]
 }
 
 // AssignTask assigns the highest priority pending task to an available worker.
-// Priority order: High > Medium > Low. For same priority, use FIFO (earliest CreatedAt first).
-// A worker is available if: active, TaskCount < MaxTasks.
+// Priority order: High > Medium > Low. For same priority, use FIFO (earliest CreatedAt first,
+// then submission order).
+// Tasks waiting out a retry backoff are skipped until their NextAttemptAt.
+// A worker is available if: active, TaskCount < MaxTasks. The available worker with the
+// most free capacity is chosen; for a tie, the one added first.
 // Returns (taskID, workerID, true) if assignment made.
 // Returns ("", "", false) if no pending tasks or no available workers.
 // Updates task status to Running and sets StartedAt.
+// The worker holds the task under a lease of the queue's lease duration,
+// extended by Heartbeat, and a new FencingToken.
+// Runs in O(log n) of the pending tasks and active workers.
+// While Start is in effect the runtime assigns tasks itself; tasks assigned
+// here are left for the caller to complete, even if they have a Handler.
 func (tq *TaskQueue) AssignTask() (string, string, bool) {
-	if len(tq.tasks) == 0 {
-		return "", "", false
-	}
+	taskID, workerID, _, ok := tq.AssignTaskWithToken()
+	return taskID, workerID, ok
+}
 
-	tasks := make([]*Task, 0, len(tq.tasks))
-	for _, t := range tq.tasks {
-		if t.Status == StatusPending {
-			tasks = append(tasks, t)
-		}
-	}
+// AssignTaskWithToken is AssignTask that also returns the assignment's
+// FencingToken, for the worker to pass to CompleteTaskWithToken,
+// FailTaskWithToken and HeartbeatWithToken.
+func (tq *TaskQueue) AssignTaskWithToken() (string, string, uint64, bool) {
+	tq.mu.Lock()
+	defer tq.unlock()
 
-	if len(tasks) == 0 {
-		return "", "", false
+	task, worker := tq.assign()
+	if task == nil {
+		return "", "", 0, false
 	}
+	return task.ID, worker.ID, task.FencingToken, true
+}
 
-	slices.SortFunc(tasks, func(a, b *Task) int {
-		if a.Priority != b.Priority {
-			return int(b.Priority) - int(a.Priority)
-		}
-		return a.CreatedAt.Compare(b.CreatedAt)
-	})
-
-	assigningTask := tasks[0]
-
-	for _, worker := range tq.workers {
-		if worker.IsActive && worker.TaskCount < worker.MaxTasks {
-			assigningTask.Status = StatusRunning
-			assigningTask.WorkerID = worker.ID
-			assigningTask.StartedAt = time.Now()
-			worker.TaskCount++
-			return assigningTask.ID, worker.ID, true
-		}
+// assign gives the next pending task to the most available worker.
+// Returns nils if there is no task or no worker.
+func (tq *TaskQueue) assign() (*Task, *Worker) {
+	now := tq.clock.Now()
+	tq.promoteDue(now)
+	worker := tq.availableWorker()
+	if len(tq.pending) == 0 || worker == nil {
+		return nil, nil
 	}
 
-	return "", "", false
+	assigningTask := heap.Pop(&tq.pending).(*Task)
+	assigningTask.Status = StatusRunning
+	assigningTask.WorkerID = worker.ID
+	assigningTask.StartedAt = now
+	tq.lastToken++
+	assigningTask.FencingToken = tq.lastToken
+	assigningTask.LeaseExpiresAt = now.Add(tq.leaseDuration)
+	heap.Push(&tq.leases, assigningTask)
+	tq.setTaskCount(worker, worker.TaskCount+1)
+	tq.publish(EventTaskAssigned, TaskAssigned{
+		TaskID:         assigningTask.ID,
+		WorkerID:       worker.ID,
+		FencingToken:   assigningTask.FencingToken,
+		LeaseExpiresAt: assigningTask.LeaseExpiresAt,
+		At:             assigningTask.StartedAt,
+	})
+	return assigningTask, worker
 }
 
 // CompleteTask marks a task as completed.
 // Returns false if task doesn't exist, not running, or workerID doesn't match.
 // Decrements worker's TaskCount.
+// A worker that may have lost its lease should use CompleteTaskWithToken:
+// once a task is reclaimed and assigned to the same worker again, the worker
+// ID no longer tells the previous assignment from the current one.
 func (tq *TaskQueue) CompleteTask(taskID, workerID string) bool {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	task, worker, ok := tq.runningTask(taskID, workerID)
+	if !ok {
+		return false
+	}
+	tq.complete(task, worker)
+	return true
+}
+
+// runningTask looks up a task that is running on the given worker.
+func (tq *TaskQueue) runningTask(taskID, workerID string) (*Task, *Worker, bool) {
 	task, ok := tq.tasks[taskID]
 	if !ok || task.Status != StatusRunning || task.WorkerID != workerID {
-		return false
+		return nil, nil, false
 	}
 
 	worker, ok := tq.workers[workerID]
 	if !ok {
-		return false
+		return nil, nil, false
 	}
+	return task, worker, true
+}
 
-	worker.TaskCount--
-	task.CompletedAt = time.Now()
+func (tq *TaskQueue) complete(task *Task, worker *Worker) {
+	tq.unassign(task, worker)
+	task.CompletedAt = tq.clock.Now()
 	task.Status = StatusCompleted
-	return true
+	tq.publish(EventTaskCompleted, TaskCompleted{
+		TaskID:   task.ID,
+		WorkerID: worker.ID,
+		At:       task.CompletedAt,
+	})
+	tq.releaseDependents(task)
+	tq.wake()
 }
 
 // FailTask marks a task as failed and handles retry logic.
-// If RetryCount < MaxRetries: increment RetryCount, set status back to Pending, clear WorkerID.
-// If RetryCount >= MaxRetries: set status to Failed.
+// If RetryCount < MaxRetries: increment RetryCount, set status back to Pending, clear WorkerID,
+// and set NextAttemptAt from the task's RetryPolicy.
+// If RetryCount >= MaxRetries: set status to Failed and move the task to the dead-letter list.
+// See FailTaskWithReason to record why.
 // Returns false if task doesn't exist, not running, or workerID doesn't match.
+// A worker that may have lost its lease should use FailTaskWithToken.
 // Decrements worker's TaskCount in both cases.
 func (tq *TaskQueue) FailTask(taskID, workerID string) bool {
-	task, ok := tq.tasks[taskID]
-	if !ok || task.Status != StatusRunning || task.WorkerID != workerID {
-		return false
-	}
+	tq.mu.Lock()
+	defer tq.unlock()
 
-	worker, ok := tq.workers[workerID]
+	task, worker, ok := tq.runningTask(taskID, workerID)
 	if !ok {
 		return false
 	}
+	tq.fail(task, worker, "")
+	return true
+}
+
+func (tq *TaskQueue) fail(task *Task, worker *Worker, reason string) {
+	now := tq.clock.Now()
+	task.Failures = append(task.Failures, FailedAttempt{
+		WorkerID:  worker.ID,
+		Reason:    reason,
+		StartedAt: task.StartedAt,
+		FailedAt:  now,
+	})
+	tq.unassign(task, worker)
+	tq.wake()
 
 	if task.RetryCount < task.MaxRetries {
-		task.Status = StatusPending
 		task.WorkerID = ""
 		task.RetryCount++
-		worker.TaskCount--
-		return true
+		task.NextAttemptAt = time.Time{}
+		if backoff := task.RetryPolicy.Backoff(task.RetryCount); backoff > 0 {
+			task.NextAttemptAt = now.Add(backoff)
+		}
+		tq.enqueue(task)
+		tq.publishFailure(EventTaskRetrying, task, worker.ID, reason, now)
+		return
 	}
 
-	worker.TaskCount--
 	task.Status = StatusFailed
-	return true
+	tq.deadLetters = append(tq.deadLetters, task)
+	tq.publishFailure(EventTaskFailed, task, worker.ID, reason, now)
+	tq.failDependents(task)
+}
+
+func (tq *TaskQueue) publishFailure(eventName string, task *Task, workerID, reason string, at time.Time) {
+	tq.publish(eventName, TaskFailed{
+		TaskID:        task.ID,
+		WorkerID:      workerID,
+		Reason:        reason,
+		RetryCount:    task.RetryCount,
+		MaxRetries:    task.MaxRetries,
+		NextAttemptAt: task.NextAttemptAt,
+		At:            at,
+	})
 }
 
 // GetPendingTasks returns all pending tasks sorted by priority (high to low),
-// then by CreatedAt (earliest first) for same priority.
+// then by CreatedAt (earliest first) for same priority: the order AssignTask
+// takes them in, including those waiting out a retry backoff or on their
+// dependencies. Only pending tasks are looked at, not the whole history.
 func (tq *TaskQueue) GetPendingTasks() []*Task {
-	tasks := make([]*Task, 0, len(tq.tasks))
-	for _, t := range tq.tasks {
-		if t.Status == StatusPending {
-			tasks = append(tasks, t)
-		}
-	}
-
-	slices.SortFunc(tasks, func(a, b *Task) int {
-		if a.Priority != b.Priority {
-			return int(b.Priority) - int(a.Priority)
-		}
-		return a.CreatedAt.Compare(b.CreatedAt)
-	})
+	tq.mu.Lock()
+	defer tq.unlock()
 
+	tasks := slices.Concat([]*Task(tq.pending), []*Task(tq.delayed))
+	for _, t := range tq.blocked {
+		tasks = append(tasks, t)
+	}
+	slices.SortFunc(tasks, compareTasks)
 	return tasks
 }
 
 // GetWorkerTasks returns all tasks currently assigned to a worker (status = Running).
 // Returns empty slice if worker doesn't exist or has no tasks.
 func (tq *TaskQueue) GetWorkerTasks(workerID string) []*Task {
+	tq.mu.Lock()
+	defer tq.unlock()
+
 	tasks := make([]*Task, 0, len(tq.tasks))
 	for _, t := range tq.tasks {
 		if t.WorkerID != "" && t.WorkerID == workerID && t.Status == StatusRunning {
@@ -254,6 +430,9 @@ func (tq *TaskQueue) GetWorkerTasks(workerID string) []*Task {
 // GetQueueStats returns statistics about the queue.
 // Returns: (pendingCount, runningCount, completedCount, failedCount)
 func (tq *TaskQueue) GetQueueStats() (int, int, int, int) {
+	tq.mu.Lock()
+	defer tq.unlock()
+
 	pendingCount := 0
 	runningCount := 0
 	completedCount := 0
@@ -284,18 +463,20 @@ func (tq *TaskQueue) GetQueueStats() (int, int, int, int) {
 // the given duration and resets them to pending (simulating worker timeout).
 // Decrements the original worker's TaskCount.
 // Returns the count of tasks reassigned.
+//
+// Deprecated: this takes long-running tasks from healthy workers too. Have
+// workers call Heartbeat and reclaim tasks with ReclaimExpiredLeases, which
+// the runtime started by Start does by itself.
 func (tq *TaskQueue) ReassignAbandonedTasks(timeout time.Duration) int {
+	tq.mu.Lock()
+	defer tq.unlock()
+
+	now := tq.clock.Now()
 	reassignCount := 0
 	for _, t := range tq.tasks {
-		if t.Status == StatusRunning && time.Since(t.StartedAt) > timeout {
+		if t.Status == StatusRunning && now.Sub(t.StartedAt) > timeout {
 			reassignCount++
-			t.Status = StatusPending
-			t.StartedAt = time.Time{}
-			t.CompletedAt = time.Time{}
-			if w, ok := tq.workers[t.WorkerID]; ok {
-				w.TaskCount--
-			}
-			t.WorkerID = ""
+			tq.requeue(t, tq.workers[t.WorkerID])
 		}
 	}
 